@@ -1,5 +1,9 @@
 package parlex
 
+import (
+	"strings"
+)
+
 type strErr string
 
 func (err strErr) Error() string { return string(err) }
@@ -11,3 +15,22 @@ const (
 	ErrCouldNotReduce = strErr("Could Not Reduce")
 	ErrBadGrammar     = strErr("Bad Grammar")
 )
+
+// Conflict describes a symbol that would have two meanings if two grammars or
+// lexers were combined.
+type Conflict struct {
+	Symbol string
+	Reason string
+}
+
+// ConflictError is returned when combining grammars or lexers would change the
+// meaning of some symbols. Nothing is combined when it is returned.
+type ConflictError []Conflict
+
+func (err ConflictError) Error() string {
+	strs := make([]string, len(err))
+	for i, c := range err {
+		strs[i] = c.Symbol + " (" + c.Reason + ")"
+	}
+	return "Conflicting Symbols: " + strings.Join(strs, ", ")
+}
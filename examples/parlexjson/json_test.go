@@ -3,6 +3,7 @@ package parlexjson
 import (
 	"bytes"
 	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/parlextest"
 	"github.com/adamcolton/parlex/tree"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -59,3 +60,12 @@ func TestParseFailures(t *testing.T) {
 	_, err = runner.Run(s)
 	assert.Error(t, err)
 }
+
+func TestCorpus(t *testing.T) {
+	cov := (&parlextest.Corpus{
+		Dir:     "testdata",
+		Runner:  runner,
+		Grammar: grmr,
+	}).Run(t)
+	t.Log(cov)
+}
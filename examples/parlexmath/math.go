@@ -19,6 +19,8 @@ const lexerRules = `
 `
 
 const grammarRules = `
+  %left op2
+  %left op1
   E -> E op2 E
     -> E op1 E
     -> number
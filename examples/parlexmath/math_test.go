@@ -16,6 +16,10 @@ func TestMath(t *testing.T) {
 		"2*-3":          -6,
 		"-1.5*4":        -6,
 		"11--11":        22,
+		"10-2-3":        5,
+		"8/4/2":         1,
+		"2*3+4*5":       26,
+		"1-2*3-4":       -9,
 	}
 	for str, expected := range tt {
 		t.Run(str, func(t *testing.T) {
@@ -0,0 +1,163 @@
+package grammar
+
+import (
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/symbol/setsymbol"
+)
+
+// Import adds the non-terminals of another grammar with their names prefixed,
+// so with the prefix "expr." the non-terminal E becomes expr.E. Terminals are
+// not renamed; they are shared through the lexer. Productions in g refer to the
+// imported non-terminals by their prefixed names. If other declares operator
+// precedence, it's levels are added after the levels of g.
+//
+// If a prefixed non-terminal is already defined in g, a terminal of other is a
+// non-terminal in g or an operator is declared in both grammars with a different
+// level or associativity, a parlex.ConflictError is returned and g is not
+// changed.
+func (g *Grammar) Import(prefix string, other parlex.Grammar) error {
+	return g.merge(other, func(name string) string {
+		return prefix + name
+	})
+}
+
+// Merge adds the non-terminals of another grammar without renaming them.
+// Symbols that g uses but does not define can be defined by other. Conflicts
+// are reported the same way as Import.
+func (g *Grammar) Merge(other parlex.Grammar) error {
+	return g.merge(other, nil)
+}
+
+func (g *Grammar) merge(other parlex.Grammar, rename func(string) string) error {
+	nts := other.NonTerminals()
+	defined := make(map[string]bool, len(nts))
+	for _, nt := range nts {
+		defined[nt.String()] = true
+	}
+	name := func(s string) string {
+		if rename != nil && defined[s] {
+			return rename(s)
+		}
+		return s
+	}
+
+	var conflicts parlex.ConflictError
+	reported := make(map[string]bool)
+	for _, nt := range nts {
+		if n := name(nt.String()); g.defines(n) {
+			conflicts = append(conflicts, parlex.Conflict{
+				Symbol: n,
+				Reason: "defined in both grammars",
+			})
+		}
+		for i := other.Productions(nt).Iter(); i.Next(); {
+			for j := i.Iter(); j.Next(); {
+				s := j.Symbol.String()
+				if defined[s] || reported[s] || !g.defines(s) {
+					continue
+				}
+				reported[s] = true
+				conflicts = append(conflicts, parlex.Conflict{
+					Symbol: s,
+					Reason: "terminal in one grammar and non-terminal in the other",
+				})
+			}
+		}
+	}
+	og, _ := other.(*Grammar)
+	if og != nil {
+		conflicts = append(conflicts, g.precedenceConflicts(og)...)
+	}
+	if conflicts != nil {
+		return conflicts
+	}
+
+	for _, nt := range nts {
+		from := g.set.Str(name(nt.String()))
+		for i := other.Productions(nt).Iter(); i.Next(); {
+			g.Add(from, g.production(i.Production, name))
+		}
+	}
+	if og != nil {
+		g.declareLevels(og)
+	}
+	return nil
+}
+
+// Override replaces the productions of non-terminals that are already defined.
+// The productions string uses the same format as New. Operators declared in the
+// string replace any declaration of the same operator in g. If any non-terminal
+// in the string is not defined in g, a parlex.ConflictError is returned and g
+// is not changed.
+func (g *Grammar) Override(productions string) error {
+	return g.update(productions, true)
+}
+
+// Extend adds productions to non-terminals that are already defined. The
+// productions string uses the same format as New. If any non-terminal in the
+// string is not defined in g or an operator is declared with a different level
+// or associativity than in g, a parlex.ConflictError is returned and g is not
+// changed.
+func (g *Grammar) Extend(productions string) error {
+	return g.update(productions, false)
+}
+
+func (g *Grammar) update(productions string, replace bool) error {
+	other, err := New(productions)
+	if err != nil {
+		return err
+	}
+	nts := other.NonTerminals()
+	var conflicts parlex.ConflictError
+	for _, nt := range nts {
+		if !g.defines(nt.String()) {
+			conflicts = append(conflicts, parlex.Conflict{
+				Symbol: nt.String(),
+				Reason: "not defined",
+			})
+		}
+	}
+	precConflicts := g.precedenceConflicts(other)
+	if !replace {
+		conflicts = append(conflicts, precConflicts...)
+	}
+	if conflicts != nil {
+		return conflicts
+	}
+
+	same := func(s string) string { return s }
+	for _, nt := range nts {
+		idx := g.set.Str(nt.String()).Idx()
+		prods := g.set.Productions()
+		if !replace {
+			prods = g.productions[idx]
+		}
+		for i := other.Productions(nt).Iter(); i.Next(); {
+			prods.AddProductions(g.production(i.Production, same))
+		}
+		g.productions[idx] = prods
+	}
+	for _, c := range precConflicts {
+		g.undeclare(c.Symbol)
+	}
+	g.declareLevels(other)
+	return nil
+}
+
+// defines returns true if name is a non-terminal of g.
+func (g *Grammar) defines(name string) bool {
+	if !g.set.Has(name) {
+		return false
+	}
+	idx := g.set.Str(name).Idx()
+	return idx < len(g.productions) && g.productions[idx] != nil
+}
+
+// production copies a production into the set of g, renaming the symbols.
+func (g *Grammar) production(p parlex.Production, name func(string) string) *setsymbol.Production {
+	prod := g.set.Production()
+	for i := p.Iter(); i.Next(); {
+		prod.AddSymbols(g.set.Str(name(i.Symbol.String())))
+	}
+	return prod
+}
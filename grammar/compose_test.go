@@ -0,0 +1,144 @@
+package grammar
+
+import (
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/stretchr/testify/assert"
+)
+
+const exprGrammar = `
+  %left op
+  E -> E op E
+    -> Atom
+  Atom -> int
+       -> ( E )
+`
+
+func TestImport(t *testing.T) {
+	expr, err := New(exprGrammar)
+	assert.NoError(t, err)
+
+	g, err := New(`
+    Stmts -> Stmt Stmts
+          ->
+    Stmt  -> id = expr.E ;
+  `)
+	assert.NoError(t, err)
+	assert.NoError(t, g.Import("expr.", expr))
+
+	expected, err := New(`
+    %left op
+    Stmts     -> Stmt Stmts
+              ->
+    Stmt      -> id = expr.E ;
+    expr.E    -> expr.E op expr.E
+              -> expr.Atom
+    expr.Atom -> int
+              -> ( expr.E )
+  `)
+	assert.NoError(t, err)
+	assert.Equal(t, expected.String(), g.String())
+
+	assert.NoError(t, g.Override(`
+    expr.Atom -> int
+              -> id
+  `))
+	assert.NoError(t, g.Extend(`
+    Stmt -> print expr.E ;
+  `))
+	expected, err = New(`
+    %left op
+    Stmts     -> Stmt Stmts
+              ->
+    Stmt      -> id = expr.E ;
+              -> print expr.E ;
+    expr.E    -> expr.E op expr.E
+              -> expr.Atom
+    expr.Atom -> int
+              -> id
+  `)
+	assert.NoError(t, err)
+	assert.Equal(t, expected.String(), g.String())
+}
+
+func TestMerge(t *testing.T) {
+	expr, err := New(exprGrammar)
+	assert.NoError(t, err)
+
+	g, err := New(`
+    Stmt -> id = E ;
+  `)
+	assert.NoError(t, err)
+	assert.NoError(t, g.Merge(expr))
+	assert.Equal(t, []string{"Stmt", "E", "Atom"}, symbolStrings(g.NonTerminals()))
+}
+
+func TestConflicts(t *testing.T) {
+	expr, err := New(exprGrammar)
+	assert.NoError(t, err)
+
+	g, err := New(`
+    Stmt -> id = E ;
+    Atom -> id
+    int  -> digit
+  `)
+	assert.NoError(t, err)
+	before := g.String()
+
+	err = g.Merge(expr)
+	expected := parlex.ConflictError{
+		{Symbol: "Atom", Reason: "defined in both grammars"},
+		{Symbol: "int", Reason: "terminal in one grammar and non-terminal in the other"},
+	}
+	assert.Equal(t, expected, err)
+	assert.Equal(t, "Conflicting Symbols: Atom (defined in both grammars), int (terminal in one grammar and non-terminal in the other)", err.Error())
+	assert.Equal(t, before, g.String())
+
+	// with a prefix only the terminal conflicts
+	err = g.Import("expr.", expr)
+	assert.Equal(t, expected[1:], err)
+
+	err = g.Override("Missing -> x")
+	assert.Equal(t, parlex.ConflictError{{Symbol: "Missing", Reason: "not defined"}}, err)
+	assert.Equal(t, before, g.String())
+}
+
+func TestPrecedenceConflicts(t *testing.T) {
+	expr, err := New(exprGrammar)
+	assert.NoError(t, err)
+
+	g, err := New(`
+    %right op
+    Stmt -> id = E ;
+  `)
+	assert.NoError(t, err)
+	before := g.String()
+
+	expected := parlex.ConflictError{{Symbol: "op", Reason: "different precedence"}}
+	assert.Equal(t, expected, g.Merge(expr))
+	assert.Equal(t, expected, g.Import("expr.", expr))
+	assert.Equal(t, expected, g.Extend("%left op\nStmt -> E ;"))
+	assert.Equal(t, before, g.String())
+
+	assert.NoError(t, g.Override("%left op\nStmt -> id = E ;"))
+	level, assoc, ok := g.Precedence("op")
+	assert.True(t, ok)
+	assert.Equal(t, 1, level)
+	assert.Equal(t, parlex.LeftAssoc, assoc)
+
+	// the same declaration in both grammars is not a conflict
+	assert.NoError(t, g.Merge(expr))
+	level, assoc, _ = g.Precedence("op")
+	assert.Equal(t, 1, level)
+	assert.Equal(t, parlex.LeftAssoc, assoc)
+	assert.Equal(t, []string{"%left op"}, g.precedenceString())
+}
+
+func symbolStrings(symbols []parlex.Symbol) []string {
+	strs := make([]string, len(symbols))
+	for i, s := range symbols {
+		strs[i] = s.String()
+	}
+	return strs
+}
@@ -1,3 +1,17 @@
 // Package grammar provides a concrete implementation of parlex.Grammar as well
 // and tools for checking for left recursion.
+//
+// It also provides transformations that return a new Grammar along with a
+// Mapping from each new production back to the productions of the original
+// grammar: LeftFactor, RemoveEpsilon, RemoveUnit, RemoveUseless, CNF and GNF.
+//
+// Operator precedence and associativity can be declared with %left, %right and
+// %nonassoc lines or with Left, Right and NonAssoc. Grammar fulfills
+// parlex.Precedencer so parsers can use the declarations to resolve ambiguous
+// productions like E -> E op E.
+//
+// Grammars can be composed. Import adds the non-terminals of another grammar
+// under a prefix, Merge adds them as they are and Override and Extend replace
+// or add to the productions of existing non-terminals. Symbols that would have
+// two meanings are reported with a parlex.ConflictError.
 package grammar
@@ -0,0 +1,17 @@
+// Package generate produces random sentences from a grammar for testing and
+// fuzzing.
+//
+// A Generator walks the grammar from the start symbol choosing productions by
+// weight. Past MaxDepth or MaxSize, only the productions with the shortest
+// derivation are chosen so generation always terminates. Terminal values come
+// from Value funcs, which can be built from a simplelexer's regular
+// expressions with Lexer.
+//
+//	g := generate.Must(grmr)
+//	g.Lexer(lxr)
+//	g.Weight("E", 0, 3)
+//	src := g.Text()
+//
+// The fuzzgen package uses a Generator with Go's native fuzzing; it is kept
+// separate so that programs using generate do not import testing.
+package generate
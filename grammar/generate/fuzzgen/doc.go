@@ -0,0 +1,10 @@
+// Package fuzzgen plugs a generate.Generator into Go's native fuzzing. The
+// fuzzer mutates the random seed rather than the text, so every input is
+// structurally valid. It is meant to be imported from tests.
+//
+//	func FuzzParse(f *testing.F) {
+//	  g := generate.Must(grmr)
+//	  g.Lexer(lxr)
+//	  fuzzgen.Fuzz(f, g, fuzzgen.Accepts(runner))
+//	}
+package fuzzgen
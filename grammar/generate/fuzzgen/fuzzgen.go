@@ -0,0 +1,40 @@
+package fuzzgen
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/grammar/generate"
+)
+
+// Seed adds n generated texts to the seed corpus of a fuzz test that takes a
+// single string.
+func Seed(f *testing.F, g *generate.Generator, n int) {
+	for i := 0; i < n; i++ {
+		f.Add(g.Text())
+	}
+}
+
+// Fuzz runs a fuzz test where the fuzzed value is the seed used to generate the
+// text passed to fn, so every input is a valid sentence of the grammar. A few
+// seeds are added to the corpus so the test also runs under go test.
+func Fuzz(f *testing.F, g *generate.Generator, fn func(t *testing.T, src string)) {
+	for i := int64(0); i < 8; i++ {
+		f.Add(i)
+	}
+	f.Fuzz(func(t *testing.T, seed int64) {
+		cp := *g
+		cp.Rand = rand.New(rand.NewSource(seed))
+		fn(t, cp.Text())
+	})
+}
+
+// Accepts returns a func for Fuzz that fails if the runner returns an error.
+func Accepts(r *parlex.Runner) func(t *testing.T, src string) {
+	return func(t *testing.T, src string) {
+		if _, err := r.Run(src); err != nil {
+			t.Errorf("%s: %q", err, src)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package fuzzgen
+
+import (
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/grammar"
+	"github.com/adamcolton/parlex/grammar/generate"
+	"github.com/adamcolton/parlex/lexer/simplelexer"
+	"github.com/adamcolton/parlex/parser/packrat"
+)
+
+var (
+	lxr = parlex.MustLexer(simplelexer.New(`
+    ( /\(/
+    ) /\)/
+    op /[+\-\*\/]/
+    int /\d+/
+    space /\s+/ -
+  `))
+	grmr = parlex.MustGrammar(grammar.New(`
+    E -> T op E
+      -> T
+    T -> ( E )
+      -> int
+  `))
+	runner = parlex.New(lxr, packrat.New(grmr))
+)
+
+func FuzzParse(f *testing.F) {
+	g := generate.Must(grmr)
+	g.Lexer(lxr.(*simplelexer.Lexer))
+	Fuzz(f, g, Accepts(runner))
+}
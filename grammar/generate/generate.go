@@ -0,0 +1,258 @@
+package generate
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/lexeme"
+	"github.com/adamcolton/parlex/symbol/stringsymbol"
+	"github.com/adamcolton/parlex/tree"
+)
+
+type strErr string
+
+func (e strErr) Error() string { return string(e) }
+
+// ErrNoTermination is returned by New if the start symbol cannot derive a
+// sentence of only terminals.
+const ErrNoTermination = strErr("Start symbol never terminates")
+
+// Defaults for a new Generator
+const (
+	DefaultMaxDepth = 12
+	DefaultMaxSize  = 64
+)
+
+// Generator produces random sentences from a grammar. Once the tree is deeper
+// than MaxDepth or the sentence has more than MaxSize terminals, only the
+// productions that terminate fastest are chosen. Both bounds are soft, the
+// sentence is always completed.
+//
+// Values maps a terminal to a func that produces it's value, terminals without
+// a Value func use their kind as their value. Sep is placed between values by
+// Text.
+type Generator struct {
+	Rand     *rand.Rand
+	MaxDepth int
+	MaxSize  int
+	Values   map[string]func(*rand.Rand) string
+	Sep      string
+
+	grammar parlex.Grammar
+	start   parlex.Symbol
+	prods   map[string][]*prod
+}
+
+type prod struct {
+	symbols []parlex.Symbol
+	weight  float64
+	height  int
+}
+
+// New creates a Generator for a grammar. The first NonTerminal is the start
+// symbol.
+func New(grmr parlex.Grammar) (*Generator, error) {
+	nts := grmr.NonTerminals()
+	g := &Generator{
+		Rand:     rand.New(rand.NewSource(1)),
+		MaxDepth: DefaultMaxDepth,
+		MaxSize:  DefaultMaxSize,
+		Values:   make(map[string]func(*rand.Rand) string),
+		Sep:      " ",
+		grammar:  grmr,
+		prods:    make(map[string][]*prod),
+	}
+	if len(nts) == 0 {
+		return nil, ErrNoTermination
+	}
+	g.start = nts[0]
+	for _, nt := range nts {
+		var ps []*prod
+		for i := grmr.Productions(nt).Iter(); i.Next(); {
+			p := &prod{
+				weight: 1,
+				height: math.MaxInt32,
+			}
+			for j := i.Iter(); j.Next(); {
+				p.symbols = append(p.symbols, j.Symbol)
+			}
+			ps = append(ps, p)
+		}
+		g.prods[nt.String()] = ps
+	}
+	g.heights()
+	if g.height(g.start) == math.MaxInt32 {
+		return nil, ErrNoTermination
+	}
+	return g, nil
+}
+
+// Must wraps New and panics if there is an error
+func Must(grmr parlex.Grammar) *Generator {
+	g, err := New(grmr)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// heights finds the minimum derivation height of every production by
+// iterating until nothing changes. An empty production has a height of 1.
+func (g *Generator) heights() {
+	for changed := true; changed; {
+		changed = false
+		for _, ps := range g.prods {
+			for _, p := range ps {
+				h := 0
+				for _, s := range p.symbols {
+					if sh := g.height(s); sh > h {
+						h = sh
+					}
+				}
+				if h < math.MaxInt32 && h+1 < p.height {
+					p.height = h + 1
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+func (g *Generator) height(s parlex.Symbol) int {
+	ps, ok := g.prods[s.String()]
+	if !ok {
+		return 0
+	}
+	h := math.MaxInt32
+	for _, p := range ps {
+		if p.height < h {
+			h = p.height
+		}
+	}
+	return h
+}
+
+// Weight sets the weight of a production. The production index is the same as
+// the index in the grammar. By default all productions have a weight of 1 and a
+// weight of 0 means the production is only chosen to terminate.
+func (g *Generator) Weight(nonterminal string, production int, weight float64) *Generator {
+	ps := g.prods[nonterminal]
+	if production >= 0 && production < len(ps) {
+		ps[production].weight = weight
+	}
+	return g
+}
+
+// Value sets the func used to generate the value for a terminal.
+func (g *Generator) Value(terminal string, fn func(*rand.Rand) string) *Generator {
+	g.Values[terminal] = fn
+	return g
+}
+
+// Tree generates a random derivation tree. The leaves are the terminals with
+// their generated values.
+func (g *Generator) Tree() *tree.PN {
+	op := &genOp{
+		Generator: g,
+	}
+	return op.node(g.start, 0, nil)
+}
+
+// Lexemes generates a random sentence as a slice of lexemes.
+func (g *Generator) Lexemes() []parlex.Lexeme {
+	return g.leaves(g.Tree(), nil)
+}
+
+// Text generates random source text by joining the values of a random sentence
+// with Sep.
+func (g *Generator) Text() string {
+	return Text(g.Lexemes(), g.Sep)
+}
+
+// Text joins the values of lexemes with sep.
+func Text(lxms []parlex.Lexeme, sep string) string {
+	strs := make([]string, len(lxms))
+	for i, lx := range lxms {
+		strs[i] = lx.Value()
+	}
+	return strings.Join(strs, sep)
+}
+
+func (g *Generator) leaves(node *tree.PN, lxms []parlex.Lexeme) []parlex.Lexeme {
+	if _, ok := g.prods[node.Kind().String()]; !ok {
+		return append(lxms, node.Lexeme)
+	}
+	for _, c := range node.C {
+		lxms = g.leaves(c, lxms)
+	}
+	return lxms
+}
+
+type genOp struct {
+	*Generator
+	size int
+}
+
+func (op *genOp) node(s parlex.Symbol, depth int, parent *tree.PN) *tree.PN {
+	kind := s.String()
+	pn := &tree.PN{
+		P: parent,
+	}
+	ps, ok := op.prods[kind]
+	if !ok {
+		op.size++
+		pn.Lexeme = lexeme.New(stringsymbol.Symbol(kind)).Set(op.value(kind))
+		return pn
+	}
+	pn.Lexeme = lexeme.New(stringsymbol.Symbol(kind))
+	p := op.choose(ps, depth >= op.MaxDepth || op.size >= op.MaxSize)
+	for _, c := range p.symbols {
+		pn.C = append(pn.C, op.node(c, depth+1, pn))
+	}
+	return pn
+}
+
+func (op *genOp) value(kind string) string {
+	if fn, ok := op.Values[kind]; ok {
+		return fn(op.Rand)
+	}
+	return kind
+}
+
+// choose picks a production by weight. If bounded, only productions with the
+// minimum height are considered.
+func (op *genOp) choose(ps []*prod, bounded bool) *prod {
+	min := math.MaxInt32
+	for _, p := range ps {
+		if p.height < min {
+			min = p.height
+		}
+	}
+	var candidates []*prod
+	var total float64
+	for _, p := range ps {
+		if p.height == math.MaxInt32 || (bounded && p.height != min) {
+			continue
+		}
+		candidates = append(candidates, p)
+		total += p.weight
+	}
+	if total <= 0 {
+		// everything has a weight of 0, fallback to the shortest
+		for _, p := range candidates {
+			if p.height == min {
+				return p
+			}
+		}
+	}
+	r := op.Rand.Float64() * total
+	for _, p := range candidates {
+		r -= p.weight
+		if r < 0 && p.weight > 0 {
+			return p
+		}
+	}
+	return candidates[len(candidates)-1]
+}
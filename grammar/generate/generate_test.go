@@ -0,0 +1,99 @@
+package generate
+
+import (
+	"math/rand"
+	"regexp"
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/grammar"
+	"github.com/adamcolton/parlex/lexer/simplelexer"
+	"github.com/adamcolton/parlex/parser/packrat"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	lxr = parlex.MustLexer(simplelexer.New(`
+    ( /\(/
+    ) /\)/
+    op /[+\-\*\/]/
+    int /\d+/
+    space /\s+/ -
+  `))
+	grmr = parlex.MustGrammar(grammar.New(`
+    E -> T op E
+      -> T
+    T -> ( E )
+      -> int
+  `))
+	runner = parlex.New(lxr, packrat.New(grmr))
+)
+
+func newGenerator(t *testing.T) *Generator {
+	g, err := New(grmr)
+	assert.NoError(t, err)
+	assert.NoError(t, g.Lexer(lxr.(*simplelexer.Lexer)))
+	return g
+}
+
+func TestText(t *testing.T) {
+	g := newGenerator(t)
+	for i := 0; i < 100; i++ {
+		src := g.Text()
+		_, err := runner.Run(src)
+		assert.NoError(t, err, src)
+	}
+}
+
+func TestBounds(t *testing.T) {
+	g := newGenerator(t)
+	g.MaxDepth = 0
+	// with no depth, the shortest derivation is always chosen
+	for i := 0; i < 10; i++ {
+		lxms := g.Lexemes()
+		if assert.Len(t, lxms, 1) {
+			assert.Equal(t, "int", lxms[0].Kind().String())
+		}
+	}
+
+	g.MaxDepth = DefaultMaxDepth
+	g.Weight("E", 1, 0).Weight("T", 0, 0)
+	tr := g.Tree()
+	assert.Equal(t, "E", tr.Kind().String())
+	assert.Equal(t, 3, tr.Children())
+}
+
+func TestValue(t *testing.T) {
+	g := newGenerator(t)
+	g.MaxDepth = 0
+	g.Value("int", func(*rand.Rand) string { return "7" })
+	assert.Equal(t, "7", g.Text())
+}
+
+func TestNoTermination(t *testing.T) {
+	_, err := New(parlex.MustGrammar(grammar.New(`
+    A -> B
+    B -> A
+  `)))
+	assert.Equal(t, ErrNoTermination, err)
+}
+
+func TestRegexp(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tt := []string{
+		`\d+`,
+		`(true)|(false)`,
+		`[a-z_][a-z0-9_]{0,5}`,
+		`"([^"\\]|\\.)*"`,
+		`\d*\.?\d+`,
+		`^x?y$`,
+	}
+	for _, s := range tt {
+		re := regexp.MustCompile(`^(?:` + s + `)$`)
+		fn := MustRegexp(regexp.MustCompile(s))
+		for i := 0; i < 20; i++ {
+			str := fn(r)
+			assert.True(t, re.MatchString(str), "%s: %q", s, str)
+		}
+	}
+}
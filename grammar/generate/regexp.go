@@ -0,0 +1,126 @@
+package generate
+
+import (
+	"math/rand"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/adamcolton/parlex/lexer/simplelexer"
+)
+
+// MaxRepeat limits the number of repetitions generated for *, + and open ended
+// {n,} in a regular expression.
+var MaxRepeat = 3
+
+// Regexp returns a func that generates random strings matching re. Anchors and
+// word boundaries are ignored. When a character class contains printable ASCII
+// characters, only those are chosen.
+func Regexp(re *regexp.Regexp) (func(*rand.Rand) string, error) {
+	s, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	s = s.Simplify()
+	return func(r *rand.Rand) string {
+		var buf strings.Builder
+		gen(s, r, &buf)
+		return buf.String()
+	}, nil
+}
+
+// MustRegexp wraps Regexp and panics if there is an error
+func MustRegexp(re *regexp.Regexp) func(*rand.Rand) string {
+	fn, err := Regexp(re)
+	if err != nil {
+		panic(err)
+	}
+	return fn
+}
+
+// Lexer sets a Value func for each rule in the lexer that is not discarded
+// using the rule's regular expression.
+func (g *Generator) Lexer(lxr *simplelexer.Lexer) error {
+	for _, r := range lxr.Rules() {
+		if r.Discard {
+			continue
+		}
+		fn, err := Regexp(r.Re)
+		if err != nil {
+			return err
+		}
+		g.Values[r.Kind.String()] = fn
+	}
+	return nil
+}
+
+func gen(s *syntax.Regexp, r *rand.Rand, buf *strings.Builder) {
+	switch s.Op {
+	case syntax.OpLiteral:
+		for _, c := range s.Rune {
+			buf.WriteRune(c)
+		}
+	case syntax.OpCharClass:
+		buf.WriteRune(class(s.Rune, r))
+	case syntax.OpAnyCharNotNL:
+		buf.WriteRune(rune(' ' + r.Intn('~'-' '+1)))
+	case syntax.OpAnyChar:
+		buf.WriteRune(rune(' ' + r.Intn('~'-' '+1)))
+	case syntax.OpCapture:
+		gen(s.Sub[0], r, buf)
+	case syntax.OpConcat:
+		for _, sub := range s.Sub {
+			gen(sub, r, buf)
+		}
+	case syntax.OpAlternate:
+		gen(s.Sub[r.Intn(len(s.Sub))], r, buf)
+	case syntax.OpStar:
+		repeat(s.Sub[0], 0, MaxRepeat, r, buf)
+	case syntax.OpPlus:
+		repeat(s.Sub[0], 1, MaxRepeat, r, buf)
+	case syntax.OpQuest:
+		repeat(s.Sub[0], 0, 1, r, buf)
+	case syntax.OpRepeat:
+		max := s.Max
+		if max == -1 {
+			max = s.Min + MaxRepeat
+		}
+		repeat(s.Sub[0], s.Min, max, r, buf)
+	}
+}
+
+func repeat(s *syntax.Regexp, min, max int, r *rand.Rand, buf *strings.Builder) {
+	n := min
+	if max > min {
+		n += r.Intn(max - min + 1)
+	}
+	for i := 0; i < n; i++ {
+		gen(s, r, buf)
+	}
+}
+
+// class picks a rune from the ranges of a character class, preferring printable
+// ASCII.
+func class(ranges []rune, r *rand.Rand) rune {
+	var ascii []rune
+	for i := 0; i < len(ranges); i += 2 {
+		for c := ranges[i]; c <= ranges[i+1] && c <= '~'; c++ {
+			if c >= ' ' {
+				ascii = append(ascii, c)
+			}
+		}
+	}
+	if len(ascii) > 0 {
+		return ascii[r.Intn(len(ascii))]
+	}
+	i := 2 * r.Intn(len(ranges)/2)
+	lo, hi := ranges[i], ranges[i+1]
+	if hi > 0xD7FF && lo < 0xE000 {
+		// avoid surrogates
+		hi = 0xD7FF
+		if lo > hi {
+			return 0xE000
+		}
+	}
+	return lo + rune(r.Intn(int(hi-lo+1)))
+}
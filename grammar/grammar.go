@@ -64,12 +64,16 @@ type Grammar struct {
 	longest     int
 	totalCount  int
 	set         *setsymbol.Set
+	levels      []precLevel
+	prec        map[string]int
 }
 
 // New Grammar. The productions string should have one rule per line. A rule
 // has the form "NonTerminal -> A B C" where A,B and C are symbols for either
 // terminals or non-terminals. If there are multiple productions for a non-
 // terminal, each row after the first can omit the non-terminal, as in "-> D E".
+// Operator precedence is declared with lines like "%left + -", "%right ^" and
+// "%nonassoc ==", each line binding tighter than the lines before it.
 func New(productions string) (*Grammar, error) {
 	g := &Grammar{
 		longest: -1,
@@ -77,6 +81,12 @@ func New(productions string) (*Grammar, error) {
 	}
 	cur := -1
 	for _, line := range strings.Split(productions, "\n") {
+		if isDirective, err := g.directiveFromLine(line); isDirective {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
 		nt, prod, err := g.productionFromLine(line)
 		if err != nil {
 			return nil, err
@@ -180,7 +190,8 @@ func (g *Grammar) String() string {
 	}
 
 	format := fmt.Sprintf("%%-%ds -> %%s", longest)
-	segs := make([]string, 0, totalCount)
+	segs := make([]string, 0, totalCount+len(g.levels))
+	segs = append(segs, g.precedenceString()...)
 	for _, nt := range nonTerminals {
 		prods := g.Productions(nt)
 		iter := prods.Iter()
@@ -2,7 +2,10 @@ package grammar
 
 import (
 	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/lexeme"
 	"github.com/adamcolton/parlex/symbol/setsymbol"
+	"github.com/adamcolton/parlex/symbol/stringsymbol"
+	"github.com/adamcolton/parlex/tree"
 )
 
 type lrOp struct {
@@ -12,26 +15,56 @@ type lrOp struct {
 	cur       *setsymbol.Symbol
 	hasDirect bool
 	set       *setsymbol.Set
+	rdcr      tree.Reducer
+	inlined   map[string][]inlined
+}
+
+// inlined is a production that had non-terminals inlined into it. The wraps
+// restore the inlined non-terminals, innermost first.
+type inlined struct {
+	symbols []string
+	wraps   []wrap
+}
+
+// wrap moves the first n children of a node into a new node of kind.
+type wrap struct {
+	kind string
+	n    int
 }
 
 // RemoveLeftRecursion will convert a grammar with left recursion into one
-// without.
-func RemoveLeftRecursion(grammar parlex.Grammar) *Grammar {
+// without. Direct left recursion is replaced with a tail non-terminal, so
+//   E -> E op T
+//     -> T
+// becomes
+//   E  -> T E'
+//   E' -> op T E'
+//      ->
+// The Reducer that is returned folds the right leaning E' chains in a tree
+// from the new grammar back into the left associative shape of the original
+// grammar and removes the tail non-terminals. Non-terminals that are inlined to
+// remove indirect left recursion are restored as well, so the reduced tree has
+// the shape of a tree from the original grammar.
+func RemoveLeftRecursion(grammar parlex.Grammar) (*Grammar, tree.Reducer) {
 	nts := grammar.NonTerminals()
 	op := &lrOp{
-		in:   grammar,
-		out:  Empty(),
-		done: make([]bool, len(nts)),
-		set:  setsymbol.New(),
+		in:      grammar,
+		out:     Empty(),
+		done:    make([]bool, len(nts)),
+		set:     setsymbol.New(),
+		rdcr:    tree.Reducer{},
+		inlined: make(map[string][]inlined),
 	}
 	for _, s := range nts {
 		op.cur = op.set.Symbol(s)
 		op.hasDirect = false
 		for i := grammar.Productions(op.cur).Iter(); i.Next(); {
-			op.safeAdd(op.set.CastProduction(i.Production))
+			op.safeAdd(op.set.CastProduction(i.Production), nil)
 		}
 		if op.hasDirect {
 			op.removeDirectLeftRecursion()
+		} else if name := op.cur.String(); len(op.inlined[name]) > 0 {
+			op.rdcr[name] = op.restore(name)
 		}
 		idx := op.cur.Idx()
 		if idx >= len(op.done) {
@@ -39,10 +72,10 @@ func RemoveLeftRecursion(grammar parlex.Grammar) *Grammar {
 		}
 		op.done[op.cur.Idx()] = true
 	}
-	return op.out
+	return op.out, op.rdcr
 }
 
-func (op *lrOp) safeAdd(prod *setsymbol.Production) {
+func (op *lrOp) safeAdd(prod *setsymbol.Production, wraps []wrap) {
 	var first *setsymbol.Symbol
 	if prod.Symbols() > 0 {
 		first = prod.Symbol(0).(*setsymbol.Symbol)
@@ -52,11 +85,27 @@ func (op *lrOp) safeAdd(prod *setsymbol.Production) {
 	// processed yet
 	if first == nil || first.Idx() >= len(op.done) || !op.done[first.Idx()] {
 		op.hasDirect = op.hasDirect || (first != nil && first.Idx() == op.cur.Idx())
+		if wraps != nil {
+			// record the shape before the tail non-terminal is added
+			in := inlined{
+				symbols: make([]string, prod.Symbols()),
+				wraps:   wraps,
+			}
+			for i := range in.symbols {
+				in.symbols[i] = prod.Symbol(i).String()
+			}
+			name := op.cur.String()
+			op.inlined[name] = append(op.inlined[name], in)
+		}
 		op.out.Add(op.cur, prod)
 		return
 	}
 
 	for i := op.in.Productions(first).Iter(); i.Next(); {
+		ws := append([]wrap{{
+			kind: first.String(),
+			n:    i.Symbols(),
+		}}, wraps...)
 		newProd := op.set.Production()
 		for lead := i.Production.Iter(); lead.Next(); {
 			newProd.AddSymbols(lead.Symbol)
@@ -64,7 +113,7 @@ func (op *lrOp) safeAdd(prod *setsymbol.Production) {
 		for tail := op.getTail(prod).Iter(); tail.Next(); {
 			newProd.AddSymbols(tail.Symbol)
 		}
-		op.safeAdd(newProd)
+		op.safeAdd(newProd, ws)
 	}
 }
 
@@ -102,6 +151,86 @@ func (op *lrOp) removeDirectLeftRecursion() {
 		}
 	}
 	op.directAdd(newSym, op.set.Production())
+	op.rdcr[op.cur.String()] = foldTail(newSym.String(), op.restore(op.cur.String()))
+}
+
+// restore returns a Reduction that wraps the children of a node back into the
+// non-terminals that were inlined into the production it matches.
+func (op *lrOp) restore(name string) tree.Reduction {
+	return func(node *tree.PN) {
+		for _, in := range op.inlined[name] {
+			if !matchKinds(node, in.symbols) {
+				continue
+			}
+			for _, w := range in.wraps {
+				inner := &tree.PN{
+					Lexeme: lexeme.New(stringsymbol.Symbol(w.kind)),
+					P:      node,
+					C:      append([]*tree.PN(nil), node.C[:w.n]...),
+				}
+				for _, c := range inner.C {
+					c.P = inner
+					inner.Lexeme.(*lexeme.Lexeme).Span(c.Lexeme)
+				}
+				node.C = append([]*tree.PN{inner}, node.C[w.n:]...)
+			}
+			return
+		}
+	}
+}
+
+func matchKinds(node *tree.PN, kinds []string) bool {
+	if len(node.C) != len(kinds) {
+		return false
+	}
+	for i, c := range node.C {
+		if c.Kind().String() != kinds[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// foldTail returns a Reduction that undoes the removal of direct left
+// recursion. A node in the form
+//   E{ b, E'{ x, E'{ y, E'{} } } }
+// is folded into
+//   E{ E{ E{ b }, x }, y }
+// The restore Reduction is called on each node once it is folded.
+func foldTail(tail string, restore tree.Reduction) tree.Reduction {
+	return func(node *tree.PN) {
+		ln := len(node.C)
+		if ln == 0 || node.C[ln-1].Kind().String() != tail {
+			restore(node)
+			return
+		}
+		cs := node.C[:ln-1]
+		for t := node.C[ln-1]; len(t.C) > 0; {
+			inner := &tree.PN{
+				Lexeme: lexeme.New(node.Kind()),
+				P:      node,
+				C:      cs,
+			}
+			for _, c := range cs {
+				c.P = inner
+				inner.Lexeme.(*lexeme.Lexeme).Span(c.Lexeme)
+			}
+			tl := len(t.C)
+			next := t.C[tl-1]
+			if next.Kind().String() != tail {
+				// not from the transformed grammar, keep the whole node
+				next, tl = &tree.PN{}, tl+1
+			}
+			restore(inner)
+			cs = append([]*tree.PN{inner}, t.C[:tl-1]...)
+			t = next
+		}
+		for _, c := range cs {
+			c.P = node
+		}
+		node.C = cs
+		restore(node)
+	}
 }
 
 func (op *lrOp) directAdd(from *setsymbol.Symbol, to *setsymbol.Production) {
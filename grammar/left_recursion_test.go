@@ -1,6 +1,13 @@
 package grammar
 
 import (
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/lexeme"
+	"github.com/adamcolton/parlex/lexer/simplelexer"
+	"github.com/adamcolton/parlex/parser/packrat"
+	"github.com/adamcolton/parlex/parser/topdown"
+	"github.com/adamcolton/parlex/symbol/stringsymbol"
+	"github.com/adamcolton/parlex/tree"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -14,7 +21,7 @@ func TestRemoveLeftRecursion(t *testing.T) {
   `)
 	assert.Len(t, grmr.NonTerminals(), 1)
 	assert.NoError(t, err)
-	noRecur := RemoveLeftRecursion(grmr)
+	noRecur, rdcr := RemoveLeftRecursion(grmr)
 	expected, err := New(`
     E  -> ( E ) E'
        -> int E'
@@ -23,6 +30,7 @@ func TestRemoveLeftRecursion(t *testing.T) {
   `)
 	assert.NoError(t, err)
 	assert.Equal(t, expected.String(), noRecur.String())
+	assert.True(t, rdcr.Can(&tree.PN{Lexeme: lexeme.New(stringsymbol.Symbol("E"))}))
 
 	grmr, err = New(`
     A -> B C
@@ -32,7 +40,7 @@ func TestRemoveLeftRecursion(t *testing.T) {
       -> y
   `)
 	assert.NoError(t, err)
-	noRecur = RemoveLeftRecursion(grmr)
+	noRecur, _ = RemoveLeftRecursion(grmr)
 	expected, err = New(`
     A  -> B C
     B  -> x
@@ -44,3 +52,70 @@ func TestRemoveLeftRecursion(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expected.String(), noRecur.String())
 }
+
+func TestLeftRecursionReducer(t *testing.T) {
+	lxr := parlex.MustLexer(simplelexer.New(`
+    ( /\(/
+    ) /\)/
+    op /[+\-\*\/]/
+    int /\d+/
+    space /\s+/ -
+  `))
+	grmr, err := New(`
+    E -> E op T
+      -> T
+    T -> ( E )
+      -> int
+  `)
+	assert.NoError(t, err)
+	noRecur, rdcr := RemoveLeftRecursion(grmr)
+	td, err := topdown.New(noRecur)
+	assert.NoError(t, err)
+
+	for _, s := range []string{"1", "1-2", "1-2-3", "1-(2-3)-4", "(1-2-3)"} {
+		expected := packrat.New(grmr).Parse(lxr.Lex(s))
+		got := rdcr.Reduce(td.Parse(lxr.Lex(s)))
+		assert.True(t, tree.Equal(expected, got), s)
+		assert.Equal(t, expected.(*tree.PN).String(), got.(*tree.PN).String(), s)
+	}
+}
+
+func TestLeftRecursionReducerInlined(t *testing.T) {
+	lxr := parlex.MustLexer(simplelexer.New(`
+    x /x/
+    y /y/
+    space /\s+/ -
+  `))
+	grmrs := []string{`
+    S -> A B
+    A -> x
+    B -> A y
+  `, `
+    S -> A B
+    A -> x
+      -> y
+    B -> A y
+      -> B x
+  `, `
+    S -> B y
+    B -> A
+      -> x
+    A -> B x
+  `}
+	inputs := []string{"x x y", "y x y x x", "x x x y"}
+	for i, g := range grmrs {
+		grmr, err := New(g)
+		assert.NoError(t, err)
+		noRecur, rdcr := RemoveLeftRecursion(grmr)
+		td, err := topdown.New(noRecur)
+		assert.NoError(t, err)
+
+		lxms := lxr.Lex(inputs[i])
+		expected := packrat.New(grmr).Parse(lxms)
+		got := rdcr.Reduce(td.Parse(lxms))
+		if assert.NotNil(t, expected, inputs[i]) && assert.NotNil(t, got, inputs[i]) {
+			assert.Equal(t, expected.(*tree.PN).String(), got.(*tree.PN).String(), inputs[i])
+			assert.True(t, tree.Equal(expected, got), inputs[i])
+		}
+	}
+}
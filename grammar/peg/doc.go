@@ -0,0 +1,36 @@
+// Package peg builds a grammar from a parsing expression grammar. The grammar
+// is meant to be parsed by packrat with PEG semantics, where the alternatives
+// of a choice are tried in order and the first that matches is used.
+//
+//	# comments start with #
+//	Expr    <- Sum !.
+//	Sum     <- Product (('+' / '-') Product)*
+//	Product <- Value (('*' / '/') Value)*
+//	Value   <- [0-9]+ / '(' Sum ')'
+//
+// A rule is a name followed by <- and the expression. The expression
+// operators are
+//
+//	e1 e2   sequence
+//	e1 / e2 ordered choice
+//	e?      optional
+//	e*      zero or more
+//	e+      one or more
+//	&e      matches if e does, without using any input
+//	!e      matches if e does not, without using any input
+//	(e)     grouping
+//
+// Terminals can be literals in single or double quotes, character classes in
+// square brackets or . for any rune. These match the lexemes from
+// runelexer, so no separate lexer is needed. A name that does not have a rule
+// is a terminal that matches the kind of a lexeme, so a PEG grammar can also
+// be used with a lexer.
+//
+// Each sub-expression becomes a non-terminal named after it, so e* becomes
+//
+//	e* -> e e*
+//	   ->
+//
+// The reducer returned with the grammar flattens those into their parents and
+// removes the predicates, so the trees only have the rules and the terminals.
+package peg
@@ -0,0 +1,229 @@
+package peg
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/grammar"
+	"github.com/adamcolton/parlex/grammar/regexgram"
+	"github.com/adamcolton/parlex/lexer/runelexer"
+	"github.com/adamcolton/parlex/lexer/simplelexer"
+	"github.com/adamcolton/parlex/parser/packrat"
+	"github.com/adamcolton/parlex/symbol/stringsymbol"
+	"github.com/adamcolton/parlex/tree"
+)
+
+const lexerRules = `
+  def     /[A-Za-z_]\w*\s*<-/
+  name    /[A-Za-z_]\w*/
+  slash   /\//
+  and     /&/
+  not     /!/
+  opt     /\?/
+  star    /\*/
+  plus    /\+/
+  lp      /\(/
+  rp      /\)/
+  literal /'(\\.|[^'\\])*'|"(\\.|[^"\\])*"/
+  class   /\[(\\.|[^\]\\])*\]/
+  dot     /\./
+  comment /#[^\n]*/ -
+  space   /\s+/ -
+`
+
+const grammarRules = `
+  Grammar  -> Rule+
+  Rule     -> def Choice
+  Choice   -> SepBy(Sequence, slash)
+  Sequence -> Prefix*
+  Prefix   -> Suffix
+           -> and Suffix
+           -> not Suffix
+  Suffix   -> Primary
+           -> Primary opt
+           -> Primary star
+           -> Primary plus
+  Primary  -> name
+           -> lp Choice rp
+           -> literal
+           -> class
+           -> dot
+`
+
+var rdcr = tree.Reducer{
+	"Rule": tree.
+		PromoteChildValue(0), // the rule name and <- become the value
+	"Choice": tree.
+		RemoveAll("slash"),
+	"Prefix": tree.PromoteSingleChild,
+	"Suffix": tree.PromoteSingleChild,
+	"Primary": tree.
+		RemoveAll("lp", "rp"). // a group is left with just the Choice
+		PromoteSingleChild(),
+}
+
+var lxr = parlex.MustLexer(simplelexer.New(lexerRules))
+var grmr, grmrRdcr = regexgram.Must(grammarRules)
+var runner = parlex.New(lxr, packrat.New(grmr), tree.Merge(grmrRdcr, rdcr))
+
+// ErrDuplicateRule is returned when a rule is defined more than once.
+var ErrDuplicateRule = errors.New("Rule defined more than once")
+
+// Grammar fulfills parlex.Grammar and parlex.TerminalMatcher. The literals,
+// character classes and . are matched by the values of the lexemes.
+type Grammar struct {
+	*grammar.Grammar
+	runelexer.Terminals
+}
+
+// New takes a parsing expression grammar and returns the Grammar and a reducer
+// that removes the non-terminals added for the sub-expressions.
+func New(pegString string) (*Grammar, tree.Reducer, error) {
+	parseTree, err := runner.Run(pegString)
+	if err != nil {
+		return nil, nil, err
+	}
+	return evalGrammar(parseTree.(*tree.PN))
+}
+
+// Must calls New and panics if there is an error.
+func Must(pegString string) (*Grammar, tree.Reducer) {
+	g, r, err := New(pegString)
+	if err != nil {
+		panic(err)
+	}
+	return g, r
+}
+
+type evalOp struct {
+	order      []string
+	prods      map[string][][]string
+	generated  map[string]bool
+	predicates map[string]bool
+	terminals  runelexer.Terminals
+	err        error
+}
+
+func evalGrammar(node *tree.PN) (*Grammar, tree.Reducer, error) {
+	op := &evalOp{
+		prods:      make(map[string][][]string),
+		generated:  make(map[string]bool),
+		predicates: make(map[string]bool),
+		terminals:  make(runelexer.Terminals),
+	}
+	for _, rule := range node.C {
+		name := strings.TrimSpace(strings.TrimSuffix(rule.Value(), "<-"))
+		if _, defined := op.prods[name]; defined {
+			return nil, nil, ErrDuplicateRule
+		}
+		op.order = append(op.order, name)
+		op.prods[name] = op.choice(rule.C[0])
+	}
+	if op.err != nil {
+		return nil, nil, op.err
+	}
+
+	g := &Grammar{
+		Grammar:   grammar.Empty(),
+		Terminals: op.terminals,
+	}
+	r := make(tree.Reducer, len(op.order))
+	for _, name := range op.order {
+		for _, prod := range op.prods[name] {
+			p := make(stringsymbol.Production, len(prod))
+			for i, s := range prod {
+				p[i] = stringsymbol.Symbol(s)
+			}
+			g.Add(stringsymbol.Symbol(name), p)
+		}
+		r[name] = op.flatten
+	}
+	return g, r, nil
+}
+
+// generate defines a non-terminal for a sub-expression if it has not been
+// defined already.
+func (op *evalOp) generate(name string, prods ...[]string) string {
+	if !op.generated[name] {
+		op.generated[name] = true
+		op.order = append(op.order, name)
+		op.prods[name] = prods
+	}
+	return name
+}
+
+func (op *evalOp) choice(node *tree.PN) [][]string {
+	prods := make([][]string, len(node.C))
+	for i, seq := range node.C {
+		for _, c := range seq.C {
+			prods[i] = append(prods[i], op.expr(c))
+		}
+	}
+	return prods
+}
+
+// expr returns the symbol for an expression, adding any non-terminals it needs
+// given:
+// (A / B C)*
+// It adds
+// (A/B_C)  -> A
+//          -> B C
+// (A/B_C)* -> (A/B_C) (A/B_C)*
+//          ->
+func (op *evalOp) expr(node *tree.PN) string {
+	switch node.Kind().String() {
+	case "name":
+		return node.Value()
+	case "literal", "class", "dot":
+		name := node.Value()
+		t, err := runelexer.ParseTerminal(name)
+		if err != nil {
+			op.err = err
+		}
+		op.terminals[name] = t
+		return name
+	case "Choice":
+		prods := op.choice(node)
+		seqs := make([]string, len(prods))
+		for i, prod := range prods {
+			seqs[i] = strings.Join(prod, "_")
+		}
+		return op.generate("("+strings.Join(seqs, "/")+")", prods...)
+	case "Suffix":
+		x := op.expr(node.C[0])
+		rep := x + "*"
+		switch node.C[1].Kind().String() {
+		case "opt":
+			return op.generate(x+"?", []string{x}, nil)
+		case "plus":
+			op.generate(rep, []string{x, rep}, nil)
+			return op.generate(x+"+", []string{x, rep})
+		}
+		return op.generate(rep, []string{x, rep}, nil)
+	case "Prefix":
+		x := op.expr(node.C[1])
+		if !op.generated[x] || !strings.HasPrefix(x, "(") {
+			x = op.generate("("+x+")", []string{x})
+		}
+		name := node.C[0].Value() + x
+		op.predicates[name] = true
+		return name
+	}
+	return ""
+}
+
+// flatten promotes the children of the non-terminals added for
+// sub-expressions and removes the predicates.
+func (op *evalOp) flatten(node *tree.PN) {
+	for i := 0; i < len(node.C); i++ {
+		kind := node.C[i].Kind().String()
+		if op.predicates[kind] {
+			node.RemoveChild(i)
+			i--
+		} else if op.generated[kind] {
+			node.PromoteChildrenOf(i)
+			i--
+		}
+	}
+}
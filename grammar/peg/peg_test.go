@@ -0,0 +1,163 @@
+package peg
+
+import (
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/grammar"
+	"github.com/adamcolton/parlex/lexer/runelexer"
+	"github.com/adamcolton/parlex/lexer/simplelexer"
+	"github.com/adamcolton/parlex/parser/packrat"
+	"github.com/adamcolton/parlex/tree"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrammar(t *testing.T) {
+	g, _, err := New(`
+    # a list of numbers
+    List   <- Number (',' Number)* !.
+    Number <- [0-9]+
+  `)
+	assert.NoError(t, err)
+
+	expected, err := grammar.New(`
+    List          -> Number (','_Number)* !(.)
+    (','_Number)  -> ',' Number
+    (','_Number)* -> (','_Number) (','_Number)*
+                  ->
+    (.)           -> .
+    Number        -> [0-9]+
+    [0-9]*        -> [0-9] [0-9]*
+                  ->
+    [0-9]+        -> [0-9] [0-9]*
+  `)
+	assert.NoError(t, err)
+	assert.Equal(t, expected.String(), g.String())
+}
+
+func TestScannerless(t *testing.T) {
+	g, rdcr := Must(`
+    Sum     <- Product (('+' / '-') Product)*
+    Product <- Value (('*' / '/') Value)*
+    Value   <- Number / '(' Sum ')'
+    Number  <- [0-9]+
+  `)
+	prsr := packrat.New(g).PEG()
+	lxr := runelexer.New()
+
+	pn := rdcr.Reduce(prsr.Parse(lxr.Lex("12+3*(4-5)")))
+	expected, err := tree.New(`
+    Sum {
+      Product {
+        Value {
+          Number {
+            [0-9]: "1"
+            [0-9]: "2"
+          }
+        }
+      }
+      '+': "+"
+      Product {
+        Value {
+          Number {
+            [0-9]: "3"
+          }
+        }
+        '*': "*"
+        Value {
+          '(': "("
+          Sum {
+            Product {
+              Value {
+                Number {
+                  [0-9]: "4"
+                }
+              }
+            }
+            '-': "-"
+            Product {
+              Value {
+                Number {
+                  [0-9]: "5"
+                }
+              }
+            }
+          }
+          ')': ")"
+        }
+      }
+    }
+  `)
+	assert.NoError(t, err)
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, expected.String(), pn.(*tree.PN).String())
+	}
+
+	assert.Nil(t, prsr.Parse(lxr.Lex("12+")))
+	// the terminals are also matched without PEG semantics
+	assert.NotNil(t, packrat.New(g).Parse(lxr.Lex("12+3")))
+}
+
+func TestPredicatesAndLiterals(t *testing.T) {
+	g, rdcr := Must(`
+    Words   <- (Keyword / Ident) (' '+ (Keyword / Ident))*
+    Keyword <- ('if' / "else") ![a-z]
+    Ident   <- !Keyword [a-z]+
+  `)
+	prsr := packrat.New(g).PEG()
+
+	rdcr = tree.Merge(rdcr, tree.Reducer{
+		"Words": tree.RemoveAll("' '"),
+	})
+	pn := rdcr.Reduce(prsr.Parse(runelexer.New().Lex("if iffy")))
+	expected, err := tree.New(`
+    Words {
+      Keyword {
+        'if': "if"
+      }
+      Ident {
+        [a-z]: "i"
+        [a-z]: "f"
+        [a-z]: "f"
+        [a-z]: "y"
+      }
+    }
+  `)
+	assert.NoError(t, err)
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, expected.String(), pn.(*tree.PN).String())
+	}
+}
+
+func TestWithLexer(t *testing.T) {
+	lxr := parlex.MustLexer(simplelexer.New(`
+    a
+    b
+    space /\s+/ -
+  `))
+	g, _ := Must(`
+    S <- A b
+    A <- a / a b
+  `)
+	prsr := packrat.New(g).PEG()
+	assert.NotNil(t, prsr.Parse(lxr.Lex("a b")))
+	assert.Nil(t, prsr.Parse(lxr.Lex("a b b")))
+}
+
+func TestErrors(t *testing.T) {
+	_, _, err := New(`
+    A <- a
+    A <- b
+  `)
+	assert.Equal(t, ErrDuplicateRule, err)
+
+	_, _, err = New(`
+    A <- []
+  `)
+	assert.Equal(t, runelexer.ErrBadTerminal, err)
+
+	_, _, err = New(`
+    A <- (a
+  `)
+	assert.Error(t, err)
+}
@@ -0,0 +1,153 @@
+package grammar
+
+import (
+	"strings"
+
+	"github.com/adamcolton/parlex"
+)
+
+type precLevel struct {
+	assoc     parlex.Assoc
+	operators []string
+}
+
+var directives = map[string]parlex.Assoc{
+	"%left":     parlex.LeftAssoc,
+	"%right":    parlex.RightAssoc,
+	"%nonassoc": parlex.NonAssoc,
+}
+
+// Declare adds a precedence level for the operators. Each call adds a level
+// that binds tighter than the ones before it. An operator is either a terminal
+// or the value of an operator lexeme.
+func (g *Grammar) Declare(assoc parlex.Assoc, operators ...string) *Grammar {
+	if g.prec == nil {
+		g.prec = make(map[string]int)
+	}
+	g.levels = append(g.levels, precLevel{
+		assoc:     assoc,
+		operators: operators,
+	})
+	for _, o := range operators {
+		g.prec[o] = len(g.levels)
+	}
+	return g
+}
+
+// Left declares a level of left associative operators.
+func (g *Grammar) Left(operators ...string) *Grammar {
+	return g.Declare(parlex.LeftAssoc, operators...)
+}
+
+// Right declares a level of right associative operators.
+func (g *Grammar) Right(operators ...string) *Grammar {
+	return g.Declare(parlex.RightAssoc, operators...)
+}
+
+// NonAssoc declares a level of non-associative operators.
+func (g *Grammar) NonAssoc(operators ...string) *Grammar {
+	return g.Declare(parlex.NonAssoc, operators...)
+}
+
+// Precedence fulfills parlex.Precedencer. Levels start at 1.
+func (g *Grammar) Precedence(operator string) (int, parlex.Assoc, bool) {
+	level, ok := g.prec[operator]
+	if !ok {
+		return 0, parlex.NonAssoc, false
+	}
+	return level, g.levels[level-1].assoc, true
+}
+
+// Directive declares a precedence level from a line like "%left + -". If the
+// line is not a precedence directive, ErrBadGrammar is returned.
+func (g *Grammar) Directive(line string) error {
+	isDirective, err := g.directiveFromLine(line)
+	if !isDirective {
+		return ErrBadGrammar
+	}
+	return err
+}
+
+// directiveFromLine handles a line like "%left + -". It returns false if the
+// line is not a directive.
+func (g *Grammar) directiveFromLine(line string) (bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "%") {
+		return false, nil
+	}
+	assoc, ok := directives[fields[0]]
+	if !ok || len(fields) == 1 {
+		return true, ErrBadGrammar
+	}
+	g.Declare(assoc, fields[1:]...)
+	return true, nil
+}
+
+// precedenceConflicts reports the operators declared in both g and other with a
+// different level or associativity.
+func (g *Grammar) precedenceConflicts(other *Grammar) parlex.ConflictError {
+	var conflicts parlex.ConflictError
+	for _, l := range other.levels {
+		for _, o := range l.operators {
+			level, assoc, ok := g.Precedence(o)
+			if ok && (level != other.prec[o] || assoc != l.assoc) {
+				conflicts = append(conflicts, parlex.Conflict{
+					Symbol: o,
+					Reason: "different precedence",
+				})
+			}
+		}
+	}
+	return conflicts
+}
+
+// declareLevels adds the levels of other after the levels of g, leaving out the
+// operators g already declares.
+func (g *Grammar) declareLevels(other *Grammar) {
+	for _, l := range other.levels {
+		var operators []string
+		for _, o := range l.operators {
+			if _, declared := g.prec[o]; !declared {
+				operators = append(operators, o)
+			}
+		}
+		if operators != nil {
+			g.Declare(l.assoc, operators...)
+		}
+	}
+}
+
+// undeclare removes an operator from its level. A level that is left empty is
+// removed and the levels after it move down.
+func (g *Grammar) undeclare(operator string) {
+	level, ok := g.prec[operator]
+	if !ok {
+		return
+	}
+	delete(g.prec, operator)
+	l := &g.levels[level-1]
+	ops := make([]string, 0, len(l.operators))
+	for _, o := range l.operators {
+		if o != operator {
+			ops = append(ops, o)
+		}
+	}
+	l.operators = ops
+	if len(ops) > 0 {
+		return
+	}
+	g.levels = append(g.levels[:level-1], g.levels[level:]...)
+	for o, lvl := range g.prec {
+		if lvl > level {
+			g.prec[o] = lvl - 1
+		}
+	}
+}
+
+func (g *Grammar) precedenceString() []string {
+	strs := make([]string, len(g.levels))
+	for i, l := range g.levels {
+		strs[i] = "%" + l.assoc.String() + " " + strings.Join(l.operators, " ")
+	}
+	return strs
+}
@@ -0,0 +1,52 @@
+package grammar
+
+import (
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrecedence(t *testing.T) {
+	g, err := New(`
+    %left + -
+    %left * /
+    %right ^
+    E -> E op E
+      -> int
+  `)
+	assert.NoError(t, err)
+
+	level, assoc, ok := g.Precedence("-")
+	assert.True(t, ok)
+	assert.Equal(t, 1, level)
+	assert.Equal(t, parlex.LeftAssoc, assoc)
+
+	level, assoc, ok = g.Precedence("^")
+	assert.True(t, ok)
+	assert.Equal(t, 3, level)
+	assert.Equal(t, parlex.RightAssoc, assoc)
+
+	_, _, ok = g.Precedence("op")
+	assert.False(t, ok)
+
+	g.NonAssoc("==")
+	level, assoc, ok = g.Precedence("==")
+	assert.True(t, ok)
+	assert.Equal(t, 4, level)
+	assert.Equal(t, parlex.NonAssoc, assoc)
+
+	expected := "%left + -\n%left * /\n%right ^\n%nonassoc ==\nE -> E op E\n  -> int"
+	assert.Equal(t, expected, g.String())
+
+	g2, err := New(g.String())
+	assert.NoError(t, err)
+	assert.Equal(t, g.String(), g2.String())
+
+	var _ parlex.Precedencer = g
+
+	_, err = New("%prec +\nE -> int")
+	assert.Equal(t, ErrBadGrammar, err)
+	_, err = New("%left\nE -> int")
+	assert.Equal(t, ErrBadGrammar, err)
+}
@@ -6,9 +6,35 @@
 //
 // A symbol or group is marked as optional by following it with ?
 //
-// A symbol or group is marked as repeating using *
+// A symbol or group is marked as repeating using *, or + to repeat it at least
+// once. A bound like {2}, {2,}, {,3} or {2,3} sets the number of repetitions.
 //
 // A set of symbols or groups can be OR'd together with |
 //
+// A symbol or group preceded by & or ! is a predicate. The parser checks that
+// it does (&) or does not (!) match at that position without using any
+// lexemes. Predicates require a parser that supports them, like packrat, and
+// the reducer removes them from the tree.
+//
+//	Ident -> !keyword id
+//
+// A macro is a parameterised rule. It is defined like a production with the
+// parameters in parenthesis after the name and used like a group with the
+// arguments separated by commas. Each use is expanded into a non-terminal that
+// the reducer flattens into it's parent, the same as a repeating group.
+//
+//	List(X)       -> X*
+//	Arguments     -> lparen SepBy(Expr, comma)? rparen
+//
+// SepBy(X, sep) and Delimited(open, X, close) are defined by default. If no
+// macro has the name, it is read as a symbol followed by a group.
+//
+// Operator precedence can be declared on it's own line with %left, %right or
+// %nonassoc followed by the operators, each line binding tighter than the
+// lines before it. See grammar.Grammar.Declare.
+//
+// A symbol can also be a literal in quotes, like '-', a character class, like
+// [0-9], or . for use with runelexer.Scannerless.
+//
 // The grammar also allows for full comments with //
 package regexgram
@@ -1,6 +1,8 @@
 package regexgram
 
 import (
+	"errors"
+	"strconv"
 	"strings"
 
 	"github.com/adamcolton/parlex"
@@ -9,14 +11,22 @@ import (
 	"github.com/adamcolton/parlex/lexer/simplelexer"
 	"github.com/adamcolton/parlex/parser/packrat"
 	"github.com/adamcolton/parlex/symbol/setsymbol"
+	"github.com/adamcolton/parlex/symbol/stringsymbol"
 	"github.com/adamcolton/parlex/tree"
 )
 
 const lexerProductions = `
   rarr     /->/
-  symbol   /\w+/
+  macro    /\w+\(/
+  comma    /,/
+  prec     /%(left|right|nonassoc)[^\n\r]*/
+  symbol   /\w+|'(\\.|[^'\\])*'|"(\\.|[^"\\])*"|\[(\\.|[^\]\\])*\]|\./
   repeats  /\*/
+  plus     /\+/
+  bound    /\{\d*(,\d*)?\}/
   optional /\?/
+  and      /&/
+  not      /!/
   or       /\|/
   (        /\(/
   )        /\)/
@@ -27,34 +37,72 @@ const lexerProductions = `
 
 const grammarProductions = `
   Grammar      -> NL Production Productions NL 
+               -> NL Precedence Productions NL 
+               -> NL MacroDef Productions NL 
   Productions  -> Production Productions
                -> ContinueProd Productions
+               -> Precedence Productions
+               -> MacroDef Productions
                -> 
+  MacroDef     -> nl macro Params ) rarr Symbols
+  Params       -> symbol comma Params
+               -> symbol
+  Precedence   -> nl prec
   Production   -> nl symbol rarr Symbols
   ContinueProd -> nl rarr Symbols
   Symbols      -> Symbol Symbols
                ->
   Symbol       -> Group
+               -> Macro
                -> OrSymbol
                -> RepSymbol
+               -> PlusSymbol
+               -> BoundSymbol
                -> OptSymbol
+               -> Predicate
                -> symbol
   Group        -> ( Symbols )
+  Macro        -> macro Args )
+  Args         -> Symbols comma Args
+               -> Symbols
   OptSymbol    -> Group optional
+               -> Macro optional
                -> symbol optional
   RepSymbol    -> Group repeats
+               -> Macro repeats
                -> symbol repeats
+  PlusSymbol   -> Group plus
+               -> Macro plus
+               -> symbol plus
+  BoundSymbol  -> Group bound
+               -> Macro bound
+               -> symbol bound
+  Predicate    -> and Group
+               -> and Macro
+               -> and symbol
+               -> not Group
+               -> not Macro
+               -> not symbol
   OrSymbol     -> Group or MoreOr
+               -> Macro or MoreOr
                -> OptSymbol or MoreOr
                -> RepSymbol or MoreOr
+               -> PlusSymbol or MoreOr
+               -> BoundSymbol or MoreOr
                -> symbol or MoreOr
   MoreOr       -> Group or MoreOr
+               -> Macro or MoreOr
                -> OptSymbol or MoreOr
                -> RepSymbol or MoreOr
+               -> PlusSymbol or MoreOr
+               -> BoundSymbol or MoreOr
                -> symbol or MoreOr
                -> Group
+               -> Macro
                -> OptSymbol
                -> RepSymbol
+               -> PlusSymbol
+               -> BoundSymbol
                -> symbol
   NL           -> nl
                ->
@@ -79,6 +127,23 @@ var rdcr = tree.Reducer{
 		RemoveChildren(0, 2). // remove new-line and rarr
 		PromoteChildValue(0). // promote the non-terminal to be the production value
 		PromoteChildrenOf(0), // replace Symbols with it's children
+	"Precedence": tree.
+		RemoveChild(0).       // remove new-line
+		PromoteChildValue(0), // promote the directive to be the value
+	"MacroDef": tree.
+		RemoveChildren(0, 3, 4). // remove new-line, ) and rarr
+		PromoteChildValue(0).    // promote the macro name to be the value
+		PromoteChildrenOf(1),    // replace Symbols with it's children
+	"Params": tree.
+		RemoveAll("comma").
+		Flatten("Params"),
+	"Macro": tree.
+		RemoveChild(-1).      // Remove )
+		PromoteChildValue(0). // promote the macro name to be the value
+		PromoteChildrenOf(0), // replace Args with the arguments
+	"Args": tree.
+		RemoveAll("comma").
+		Flatten("Args"),
 	"ContinueProd": tree.
 		RemoveChildren(0, 1). // remove new-line and rarr
 		PromoteChildrenOf(0), // replace Symbols with it's children
@@ -89,6 +154,12 @@ var rdcr = tree.Reducer{
 		RemoveChild(-1), // Remove ?
 	"RepSymbol": tree.
 		RemoveChild(-1), // Remove *
+	"PlusSymbol": tree.
+		RemoveChild(-1), // Remove +
+	"BoundSymbol": tree.
+		PromoteChildValue(-1), // promote the bounds to be the value
+	"Predicate": tree.
+		PromoteChildValue(0), // promote & or ! to be the value
 	"OrSymbol": tree.
 		RemoveChild(1).       // Remove |
 		PromoteChildrenOf(1), // promote the rest of the or condition
@@ -105,46 +176,175 @@ var lxr = parlex.MustLexer(simplelexer.New(lexerProductions)).(*simplelexer.Lexe
 var grmr = parlex.MustGrammar(grammar.New(grammarProductions))
 var prsr = packrat.New(grmr)
 
-var runner = parlex.New(lxr, prsr, rdcr)
+var runner = parlex.New(macroLexer{lxr}, prsr, rdcr)
+
+// macroLexer only reads name( as a macro if name is a builtin macro or is
+// defined in the input, otherwise it is read as a symbol followed by a group.
+type macroLexer struct {
+	parlex.Lexer
+}
+
+func (l macroLexer) Lex(str string) []parlex.Lexeme {
+	lxms := l.Lexer.Lex(str)
+	if lxms == nil || len(parlex.LexErrors(lxms)) > 0 {
+		return lxms
+	}
+	defined := make(map[string]bool, len(builtinMacros))
+	for name := range builtinMacros {
+		defined[name] = true
+	}
+	for i, lx := range lxms {
+		// a macro at the start of a line is a definition
+		if i > 0 && lx.Kind().String() == "macro" && lxms[i-1].Kind().String() == "nl" {
+			defined[strings.TrimSuffix(lx.Value(), "(")] = true
+		}
+	}
+
+	out := make([]parlex.Lexeme, 0, len(lxms))
+	for _, lx := range lxms {
+		name := strings.TrimSuffix(lx.Value(), "(")
+		if lx.Kind().String() != "macro" || defined[name] {
+			out = append(out, lx)
+			continue
+		}
+		ln, col := lx.Pos()
+		out = append(out, &lexeme.Lexeme{
+			K:  stringsymbol.Symbol("symbol"),
+			V:  name,
+			L:  ln,
+			C:  col,
+			EL: ln,
+			EC: col + len(name),
+		}, &lexeme.Lexeme{
+			K:  stringsymbol.Symbol("("),
+			V:  "(",
+			L:  ln,
+			C:  col + len(name),
+			EL: ln,
+			EC: col + len(name) + 1,
+		})
+	}
+	return out
+}
+
+// Errors from expanding macros
+var (
+	ErrUndefinedMacro = errors.New("Undefined macro")
+	ErrMacroArgs      = errors.New("Wrong number of macro arguments")
+	ErrBadBound       = errors.New("Bad repetition bound")
+)
+
+const builtinMacroDefs = `
+  SepBy(X, sep)             -> X (sep X)*
+  Delimited(open, X, close) -> open X close
+`
+
+var builtinMacros = mustMacros(builtinMacroDefs)
+
+// macro is a parameterised rule. Each body is the symbols of one production.
+type macro struct {
+	params []string
+	bodies [][]*tree.PN
+}
 
 type evalOp struct {
-	grammar   *grammar.Grammar
-	set       *setsymbol.Set
-	rdcr      tree.Reducer
-	stack     []*tree.PN
-	nonterm   string
-	bludgeons map[string][]string
-	done      map[string]rules
+	grammar    *grammar.Grammar
+	set        *setsymbol.Set
+	rdcr       tree.Reducer
+	stack      []*tree.PN
+	nonterm    string
+	bludgeons  map[string][]string
+	predicates map[string][]string
+	done       map[string]rules
+	macros     map[string]*macro
+	err        error
 }
 
-func evalGrammar(node *tree.PN) (*grammar.Grammar, tree.Reducer) {
+func newEvalOp() *evalOp {
 	op := &evalOp{
-		grammar:   grammar.Empty(),
-		set:       setsymbol.New(),
-		rdcr:      tree.Reducer{},
-		bludgeons: make(map[string][]string),
-		done:      make(map[string]rules),
+		grammar:    grammar.Empty(),
+		set:        setsymbol.New(),
+		rdcr:       tree.Reducer{},
+		bludgeons:  make(map[string][]string),
+		predicates: make(map[string][]string),
+		done:       make(map[string]rules),
+		macros:     make(map[string]*macro, len(builtinMacros)),
 	}
-	for _, c := range node.C {
-		if c.Kind().String() == "Production" {
-			op.nonterm = c.Value()
-		} else {
-			c.Lexeme.(*lexeme.Lexeme).V = op.nonterm
-		}
-		op.stack = append(op.stack, c)
+	for name, m := range builtinMacros {
+		op.macros[name] = m
+	}
+	return op
+}
+
+func mustMacros(str string) map[string]*macro {
+	parseTree, err := runner.Run(str)
+	if err != nil {
+		panic(err)
 	}
+	op := &evalOp{
+		macros: make(map[string]*macro),
+	}
+	op.collect(parseTree.(*tree.PN))
+	return op.macros
+}
+
+func evalGrammar(node *tree.PN) (*grammar.Grammar, tree.Reducer, error) {
+	op := newEvalOp()
+	op.collect(node)
 
 	for len(op.stack) > 0 {
 		node := op.stack[0]
 		op.stack = op.stack[1:]
 		op.evalProd(node)
 	}
+	if op.err != nil {
+		return nil, nil, op.err
+	}
 
 	for nonterm, symbols := range op.bludgeons {
 		op.rdcr[nonterm] = bludgeon(symbols)
 	}
+	for nonterm, symbols := range op.predicates {
+		r := tree.RemoveAll(symbols...)
+		if b, ok := op.rdcr[nonterm]; ok {
+			r = tree.Chain(r, b)
+		}
+		op.rdcr[nonterm] = r
+	}
 
-	return op.grammar, op.rdcr
+	return op.grammar, op.rdcr, nil
+}
+
+// collect puts the productions on the stack, defines the macros and declares
+// the precedence.
+func (op *evalOp) collect(node *tree.PN) {
+	var cur *macro
+	for _, c := range node.C {
+		switch c.Kind().String() {
+		case "Precedence":
+			op.setErr(op.grammar.Directive(c.Value()))
+			continue
+		case "MacroDef":
+			cur = &macro{
+				bodies: [][]*tree.PN{c.C[1:]},
+			}
+			for _, p := range c.C[0].C {
+				cur.params = append(cur.params, p.Value())
+			}
+			op.macros[macroName(c)] = cur
+			continue
+		case "Production":
+			op.nonterm = c.Value()
+			cur = nil
+		default:
+			if cur != nil {
+				cur.bodies = append(cur.bodies, c.C)
+				continue
+			}
+			c.Lexeme.(*lexeme.Lexeme).V = op.nonterm
+		}
+		op.stack = append(op.stack, c)
+	}
 }
 
 func (op *evalOp) evalProd(node *tree.PN) {
@@ -180,10 +380,209 @@ func (op *evalOp) evalSymbol(node *tree.PN) rules {
 		return rc.reduce()
 	case "RepSymbol":
 		return op.addRepeatAsProduction(node)
+	case "PlusSymbol":
+		return mergeRules(op.evalSymbol(node.C[0]), op.addRepeatAsProduction(repeatOf(node)))
+	case "BoundSymbol":
+		return op.evalBound(node)
+	case "Predicate":
+		return op.addPredicate(node)
+	case "Macro":
+		return op.expandMacro(node)
 	}
 	return nil
 }
 
+// repeatOf returns a RepSymbol node that repeats the child of node.
+func repeatOf(node *tree.PN) *tree.PN {
+	rep := &tree.PN{
+		Lexeme: lexeme.String("RepSymbol"),
+		C:      []*tree.PN{tree.Clone(node.C[0])},
+	}
+	rep.C[0].P = rep
+	return rep
+}
+
+// evalBound expands a bounded repetition into a rule for each number of
+// repetitions. If there is no upper bound, the minimum number are followed by
+// a repeat.
+func (op *evalOp) evalBound(node *tree.PN) rules {
+	min, max, ok := parseBound(node.Value())
+	if !ok {
+		op.setErr(ErrBadBound)
+		return nil
+	}
+	x := op.evalSymbol(node.C[0])
+	if len(x) > 1 {
+		// lift the alternatives into their own non-terminal, otherwise every
+		// combination of them would be a separate rule
+		name := op.addGroupAsProduction(node.C[0])
+		op.bludgeons[op.nonterm] = append(op.bludgeons[op.nonterm], name)
+		x = rules{rule{name}}
+	}
+	cur := rules{rule{}}
+	for i := 0; i < min; i++ {
+		cur = mergeRules(cur, x)
+	}
+	if max == -1 {
+		return mergeRules(cur, op.addRepeatAsProduction(repeatOf(node)))
+	}
+	rs := append(rules{}, cur...)
+	for i := min; i < max; i++ {
+		cur = mergeRules(cur, x)
+		rs = append(rs, cur...)
+	}
+	return rs
+}
+
+// parseBound parses {n}, {n,}, {,m} and {n,m}. A max of -1 means there is no
+// upper bound.
+func parseBound(str string) (min, max int, ok bool) {
+	str = strings.TrimSuffix(strings.TrimPrefix(str, "{"), "}")
+	if str == "" {
+		return 0, 0, false
+	}
+	bounds := strings.SplitN(str, ",", 2)
+	var err error
+	if bounds[0] != "" {
+		if min, err = strconv.Atoi(bounds[0]); err != nil {
+			return 0, 0, false
+		}
+	}
+	if len(bounds) == 1 {
+		return min, min, true
+	}
+	if bounds[1] == "" {
+		return min, -1, true
+	}
+	if max, err = strconv.Atoi(bounds[1]); err != nil || max < min {
+		return 0, 0, false
+	}
+	return min, max, true
+}
+
+// addPredicate adds a non-terminal for the target of a predicate, given
+// !(A B)
+// It adds
+// (A_B) -> A B
+// And returns the rule !(A_B) which the parser checks without using any
+// lexemes. The reducer removes the predicate from the tree.
+func (op *evalOp) addPredicate(node *tree.PN) rules {
+	symName := node.Value() + op.addGroupAsProduction(node.C[0])
+	op.predicates[op.nonterm] = append(op.predicates[op.nonterm], symName)
+	return rules{rule{symName}}
+}
+
+// addGroupAsProduction adds a non-terminal for a symbol or group and returns
+// it's name, given
+// x|y
+// It adds
+// (x|y) -> x
+//       -> y
+func (op *evalOp) addGroupAsProduction(node *tree.PN) string {
+	target := op.getName(node)
+	if node.Kind().String() != "Group" {
+		target = "(" + target + ")"
+	}
+	if _, ok := op.done[target]; !ok {
+		op.done[target] = rules{rule{target}}
+		prod := &tree.PN{
+			Lexeme: &lexeme.Lexeme{
+				K: op.set.Str("Production"),
+				V: target,
+			},
+		}
+		cp := tree.Clone(node)
+		if cp.Kind().String() == "Group" {
+			prod.C = cp.C
+		} else {
+			prod.C = []*tree.PN{cp}
+		}
+		for _, c := range prod.C {
+			c.P = prod
+		}
+		op.stack = append(op.stack, prod)
+	}
+	return target
+}
+
+func macroName(node *tree.PN) string {
+	return strings.TrimSuffix(node.Value(), "(")
+}
+
+// expandMacro adds the productions of a macro with the arguments substituted
+// for the parameters. The non-terminal is named for the macro and it's
+// arguments, given
+// SepBy(Item, comma)
+// It adds
+// SepBy(Item,comma) -> Item (comma Item)*
+// And adds a rule to the reducer so that it is flattened into it's parent
+func (op *evalOp) expandMacro(node *tree.PN) rules {
+	symName := op.getName(node)
+	op.bludgeons[op.nonterm] = append(op.bludgeons[op.nonterm], symName)
+	if rs, ok := op.done[symName]; ok {
+		return rs
+	}
+	rs := rules{rule{symName}}
+	op.done[symName] = rs
+
+	m, ok := op.macros[macroName(node)]
+	if !ok {
+		op.setErr(ErrUndefinedMacro)
+		return rs
+	}
+	if len(m.params) != len(node.C) {
+		op.setErr(ErrMacroArgs)
+		return rs
+	}
+	args := make(map[string]*tree.PN, len(m.params))
+	for i, p := range m.params {
+		args[p] = node.C[i]
+	}
+	for _, body := range m.bodies {
+		prod := &tree.PN{
+			Lexeme: &lexeme.Lexeme{
+				K: op.set.Str("Production"),
+				V: symName,
+			},
+		}
+		for _, c := range body {
+			sub := op.substitute(tree.Clone(c), args)
+			sub.P = prod
+			prod.C = append(prod.C, sub)
+		}
+		op.stack = append(op.stack, prod)
+	}
+	return rs
+}
+
+// substitute replaces the parameters in a macro body with the arguments. An
+// argument with more than one symbol becomes a group.
+func (op *evalOp) substitute(node *tree.PN, args map[string]*tree.PN) *tree.PN {
+	if node.Kind().String() == "symbol" {
+		arg, ok := args[node.Value()]
+		if !ok {
+			return node
+		}
+		cp := tree.Clone(arg)
+		if len(cp.C) == 1 {
+			return cp.C[0]
+		}
+		cp.Lexeme.(*lexeme.Lexeme).K = op.set.Str("Group")
+		return cp
+	}
+	for i, c := range node.C {
+		node.C[i] = op.substitute(c, args)
+		node.C[i].P = node
+	}
+	return node
+}
+
+func (op *evalOp) setErr(err error) {
+	if op.err == nil {
+		op.err = err
+	}
+}
+
 // addRepeatAsProduction creates two productions
 // given:
 // E*
@@ -249,6 +648,12 @@ func (op *evalOp) getName(node *tree.PN) string {
 		return op.getName(node.C[0]) + "?"
 	case "RepSymbol":
 		return op.getName(node.C[0]) + "*"
+	case "PlusSymbol":
+		return op.getName(node.C[0]) + "+"
+	case "BoundSymbol":
+		return op.getName(node.C[0]) + node.Value()
+	case "Predicate":
+		return node.Value() + op.getName(node.C[0])
 	case "OrSymbol":
 		var strs []string
 		for _, c := range node.C {
@@ -261,6 +666,21 @@ func (op *evalOp) getName(node *tree.PN) string {
 			strs = append(strs, op.getName(c))
 		}
 		return "(" + strings.Join(strs, "_") + ")"
+	case "Symbols":
+		if len(node.C) == 1 {
+			return op.getName(node.C[0])
+		}
+		var strs []string
+		for _, c := range node.C {
+			strs = append(strs, op.getName(c))
+		}
+		return "(" + strings.Join(strs, "_") + ")"
+	case "Macro":
+		var strs []string
+		for _, c := range node.C {
+			strs = append(strs, op.getName(c))
+		}
+		return macroName(node) + "(" + strings.Join(strs, ",") + ")"
 	}
 
 	return ""
@@ -292,7 +712,7 @@ func mergeRules(a, b rules) rules {
 
 func (rc ruleComb) reduce() rules {
 	if len(rc) == 0 {
-		return nil
+		return rules{rule{}}
 	}
 	ra := rc[0]
 	for _, rb := range rc[1:] {
@@ -307,8 +727,7 @@ func New(grammarString string) (*grammar.Grammar, tree.Reducer, error) {
 	if err != nil {
 		return nil, nil, err
 	}
-	g, r := evalGrammar(parseTree.(*tree.PN))
-	return g, r, nil
+	return evalGrammar(parseTree.(*tree.PN))
 }
 
 // Must returns a grammar and a reducer. If it fails to parse the grammar string
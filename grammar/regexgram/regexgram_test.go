@@ -1,9 +1,11 @@
 package regexgram
 
 import (
+	"github.com/adamcolton/parlex"
 	"github.com/adamcolton/parlex/grammar"
 	"github.com/adamcolton/parlex/lexer/simplelexer"
 	"github.com/adamcolton/parlex/parser/packrat"
+	"github.com/adamcolton/parlex/symbol/stringsymbol"
 	"github.com/adamcolton/parlex/tree"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -244,3 +246,223 @@ func TestMergeRulesClosure(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expectGrmr.String(), grmr.String())
 }
+
+func TestPrecedence(t *testing.T) {
+	grmr, _, err := New(`
+    %left add
+    %right pow
+    E -> E (add|pow) E
+      -> int
+  `)
+	assert.NoError(t, err)
+
+	level, assoc, ok := grmr.Precedence("pow")
+	assert.True(t, ok)
+	assert.Equal(t, 2, level)
+	assert.Equal(t, parlex.RightAssoc, assoc)
+
+	expectGrmr, err := grammar.New(`
+    %left add
+    %right pow
+    E -> E add E
+      -> E pow E
+      -> int
+  `)
+	assert.NoError(t, err)
+	assert.Equal(t, expectGrmr.String(), grmr.String())
+
+	grmr, _, err = New(`
+    %left * /
+    E -> E op E
+      -> int
+  `)
+	assert.NoError(t, err)
+	level, assoc, ok = grmr.Precedence("/")
+	assert.True(t, ok)
+	assert.Equal(t, 1, level)
+	assert.Equal(t, parlex.LeftAssoc, assoc)
+
+	_, _, err = New(`
+    %left
+    E -> int
+  `)
+	assert.Equal(t, grammar.ErrBadGrammar, err)
+}
+
+func TestMacros(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    int   /\d+/
+    comma /,/
+    open  /\[/
+    close /\]/
+  `)
+	assert.NoError(t, err)
+	grmr, rdcr := Must(`
+    List -> Delimited(open, SepBy(int, comma)?, close)
+  `)
+
+	expectGrmr, err := grammar.New(`
+    List                                       -> Delimited(open,SepBy(int,comma)?,close)
+    Delimited(open,SepBy(int,comma)?,close) -> open SepBy(int,comma) close
+                                               -> open close
+    SepBy(int,comma)                           -> int (comma_int)*
+    (comma_int)*                               -> comma int (comma_int)*
+                                               ->
+  `)
+	assert.NoError(t, err)
+	assert.Equal(t, expectGrmr.String(), grmr.String())
+
+	pn := rdcr.Reduce(packrat.New(grmr).Parse(lxr.Lex("[1,2,3]")))
+	expected, err := tree.New(`
+    List {
+      open: "["
+      int: "1"
+      comma: ","
+      int: "2"
+      comma: ","
+      int: "3"
+      close: "]"
+    }
+  `)
+	assert.NoError(t, err)
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, expected.String(), pn.(*tree.PN).String())
+	}
+
+	pn = rdcr.Reduce(packrat.New(grmr).Parse(lxr.Lex("[]")))
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, 2, pn.Children())
+	}
+}
+
+func TestUserMacros(t *testing.T) {
+	grmr, _, err := New(`
+    Call          -> id Parens(SepBy(Expr, comma))
+    Expr          -> int
+                  -> Call
+    Parens(X)     -> lp X rp
+                  -> lp rp
+  `)
+	assert.NoError(t, err)
+
+	expectGrmr, err := grammar.New(`
+    Call                      -> id Parens(SepBy(Expr,comma))
+    Expr                      -> int
+                              -> Call
+    Parens(SepBy(Expr,comma)) -> lp SepBy(Expr,comma) rp
+                              -> lp rp
+    SepBy(Expr,comma)         -> Expr (comma_Expr)*
+    (comma_Expr)*             -> comma Expr (comma_Expr)*
+                              ->
+  `)
+	assert.NoError(t, err)
+	assert.Equal(t, expectGrmr.String(), grmr.String())
+
+	// without a macro named x, x( is a symbol followed by a group
+	grmr, _, err = New(`
+    E -> x(y z)
+      -> Missing(w)*
+  `)
+	assert.NoError(t, err)
+	expectGrmr, _, err = New(`
+    E -> x (y z)
+      -> Missing (w)*
+  `)
+	assert.NoError(t, err)
+	assert.Equal(t, expectGrmr.String(), grmr.String())
+
+	_, _, err = New(`
+    A -> SepBy(x)
+  `)
+	assert.Equal(t, ErrMacroArgs, err)
+}
+
+func TestPlusAndBound(t *testing.T) {
+	grmr, _, err := New(`
+    A -> x+ y{2} z{1,}
+    B -> (x y){,2}
+  `)
+	assert.NoError(t, err)
+
+	expectGrmr, err := grammar.New(`
+    A      -> x x* y y z z*
+    B      -> 
+           -> x y
+           -> x y x y
+    x*     -> x x*
+           ->
+    z*     -> z z*
+           ->
+  `)
+	assert.NoError(t, err)
+	assert.Equal(t, expectGrmr.String(), grmr.String())
+
+	// the alternatives are lifted into a non-terminal so the bound only
+	// adds one production per count
+	grmr, rdcr, err := New(`
+    A -> (x|y|z){0,10}
+  `)
+	assert.NoError(t, err)
+	prods := 0
+	for _, nt := range grmr.NonTerminals() {
+		prods += grmr.Productions(nt).Productions()
+	}
+	assert.Equal(t, 14, prods)
+	assert.NotNil(t, grmr.Productions(stringsymbol.Symbol("(x|y|z)")))
+	lxr := parlex.MustLexer(simplelexer.New(`
+    x
+    y
+    z
+    space /\s+/ -
+  `))
+	pn := rdcr.Reduce(packrat.New(grmr).Parse(lxr.Lex("x z y")))
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, "A {\n\tx: \"x\"\n\tz: \"z\"\n\ty: \"y\"\n}\n", pn.(*tree.PN).String())
+	}
+
+	for _, bad := range []string{"{}", "{3,2}"} {
+		_, _, err = New("A -> x" + bad)
+		assert.Equal(t, ErrBadBound, err, bad)
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    space /\s+/ -
+    if    /if/
+    id    /\w+/
+  `)
+	assert.NoError(t, err)
+	grmr, rdcr := Must(`
+    Idents -> Ident+
+    Ident  -> !if id
+  `)
+
+	expectGrmr, err := grammar.New(`
+    Idents -> Ident Ident*
+    Ident  -> !(if) id
+    Ident* -> Ident Ident*
+           ->
+    (if)   -> if
+  `)
+	assert.NoError(t, err)
+	assert.Equal(t, expectGrmr.String(), grmr.String())
+
+	pn := rdcr.Reduce(packrat.New(grmr).Parse(lxr.Lex("foo bar")))
+	expected, err := tree.New(`
+    Idents {
+      Ident {
+        id: "foo"
+      }
+      Ident {
+        id: "bar"
+      }
+    }
+  `)
+	assert.NoError(t, err)
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, expected.String(), pn.(*tree.PN).String())
+	}
+
+	assert.Nil(t, packrat.New(grmr).Parse(lxr.Lex("foo if")))
+}
@@ -0,0 +1,647 @@
+package grammar
+
+import (
+	"strings"
+
+	"github.com/adamcolton/parlex"
+)
+
+// Origin identifies a production in a grammar by the non-terminal and the
+// index of the production.
+type Origin struct {
+	NonTerminal string
+	Production  int
+}
+
+// Mapping maps each production in a transformed grammar to the productions in
+// the original grammar that were used to build it. The first Origin is the
+// production that was rewritten, any others were substituted into it. A
+// production introduced by the transformation, like a new start symbol, maps
+// to nothing.
+type Mapping map[Origin][]Origin
+
+// Origins returns the productions in the original grammar that a production in
+// the transformed grammar was built from.
+func (m Mapping) Origins(nonterminal string, production int) []Origin {
+	return m[Origin{nonterminal, production}]
+}
+
+// Then composes two mappings. If m maps grammar B back to A and next maps
+// grammar C back to B, the result maps C back to A.
+func (m Mapping) Then(next Mapping) Mapping {
+	out := make(Mapping, len(next))
+	for k, os := range next {
+		var merged []Origin
+		for _, o := range os {
+			merged = mergeOrigins(merged, m[o])
+		}
+		out[k] = merged
+	}
+	return out
+}
+
+func mergeOrigins(a, b []Origin) []Origin {
+	out := append([]Origin(nil), a...)
+	for _, o := range b {
+		found := false
+		for _, o2 := range out {
+			if o == o2 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// rule is a production that is being transformed
+type rule struct {
+	syms    []string
+	origins []Origin
+}
+
+func (r *rule) key() string { return strings.Join(r.syms, " ") }
+
+func (r *rule) first() string {
+	if len(r.syms) == 0 {
+		return ""
+	}
+	return r.syms[0]
+}
+
+// work holds a grammar while it is being transformed. A symbol is a
+// non-terminal if it is in nts, even if all it's productions are removed.
+type work struct {
+	order []string
+	prods map[string][]*rule
+	nts   map[string]bool
+}
+
+func load(g parlex.Grammar) *work {
+	w := &work{
+		prods: make(map[string][]*rule),
+		nts:   make(map[string]bool),
+	}
+	for _, nt := range g.NonTerminals() {
+		name := nt.String()
+		w.order = append(w.order, name)
+		w.nts[name] = true
+		for i := g.Productions(nt).Iter(); i.Next(); {
+			r := &rule{
+				origins: []Origin{{name, i.Idx}},
+			}
+			for j := i.Iter(); j.Next(); {
+				r.syms = append(r.syms, j.Symbol.String())
+			}
+			w.prods[name] = append(w.prods[name], r)
+		}
+	}
+	return w
+}
+
+func (w *work) start() string {
+	if len(w.order) == 0 {
+		return ""
+	}
+	return w.order[0]
+}
+
+// grammar builds the Grammar and Mapping. Non-terminals with no productions
+// are left out.
+func (w *work) grammar() (*Grammar, Mapping) {
+	g := Empty()
+	m := make(Mapping)
+	for _, nt := range w.order {
+		for i, r := range w.prods[nt] {
+			prod := g.set.Production()
+			for _, s := range r.syms {
+				prod.AddSymbols(g.set.Str(s))
+			}
+			g.Add(g.set.Str(nt), prod)
+			m[Origin{nt, i}] = r.origins
+		}
+	}
+	return g, m
+}
+
+// addNT adds a new non-terminal after the non-terminal after.
+func (w *work) addNT(name, after string) {
+	w.nts[name] = true
+	for i, nt := range w.order {
+		if nt == after {
+			w.order = append(w.order[:i+1], append([]string{name}, w.order[i+1:]...)...)
+			return
+		}
+	}
+	w.order = append(w.order, name)
+}
+
+// fresh returns a new symbol by adding ' to base until it is not used.
+func (w *work) fresh(base string) string {
+	used := make(map[string]bool)
+	for _, nt := range w.order {
+		used[nt] = true
+		for _, r := range w.prods[nt] {
+			for _, s := range r.syms {
+				used[s] = true
+			}
+		}
+	}
+	name := base + "'"
+	for used[name] {
+		name += "'"
+	}
+	return name
+}
+
+// add appends a rule to the non-terminal unless an equal rule exists, in which
+// case the origins are merged.
+func add(rules []*rule, r *rule) []*rule {
+	k := r.key()
+	for _, r2 := range rules {
+		if r2.key() == k {
+			r2.origins = mergeOrigins(r2.origins, r.origins)
+			return rules
+		}
+	}
+	return append(rules, r)
+}
+
+func (w *work) usesOnRHS(nt string) bool {
+	for _, rs := range w.prods {
+		for _, r := range rs {
+			for _, s := range r.syms {
+				if s == nt {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// newStart adds a new start symbol that derives the old start symbol.
+func (w *work) newStart() {
+	old := w.start()
+	s := w.fresh(old)
+	w.nts[s] = true
+	w.order = append([]string{s}, w.order...)
+	w.prods[s] = []*rule{{syms: []string{old}}}
+}
+
+func (w *work) nullable() map[string]bool {
+	null := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, nt := range w.order {
+			if null[nt] {
+				continue
+			}
+			for _, r := range w.prods[nt] {
+				all := true
+				for _, s := range r.syms {
+					if !null[s] {
+						all = false
+						break
+					}
+				}
+				if all {
+					null[nt] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return null
+}
+
+// LeftFactor factors out common prefixes of productions so that no two
+// productions of a non-terminal start with the same symbol.
+//   A -> a b C
+//     -> a b D
+// becomes
+//   A  -> a b A'
+//   A' -> C
+//      -> D
+func LeftFactor(g parlex.Grammar) (*Grammar, Mapping) {
+	w := load(g)
+	w.leftFactor()
+	return w.grammar()
+}
+
+func (w *work) leftFactor() {
+	queue := append([]string(nil), w.order...)
+	for len(queue) > 0 {
+		nt := queue[0]
+		queue = queue[1:]
+		var out []*rule
+		done := make(map[string]bool)
+		rs := w.prods[nt]
+		for i, r := range rs {
+			f := r.first()
+			if f == "" {
+				out = append(out, r)
+				continue
+			}
+			if done[f] {
+				continue
+			}
+			done[f] = true
+			group := []*rule{r}
+			for _, r2 := range rs[i+1:] {
+				if r2.first() == f {
+					group = append(group, r2)
+				}
+			}
+			if len(group) == 1 {
+				out = append(out, r)
+				continue
+			}
+			prefix := commonPrefix(group)
+			tail := w.fresh(nt)
+			w.addNT(tail, nt)
+			factored := &rule{
+				syms: append(append([]string(nil), prefix...), tail),
+			}
+			for _, gr := range group {
+				factored.origins = mergeOrigins(factored.origins, gr.origins)
+				w.prods[tail] = add(w.prods[tail], &rule{
+					syms:    append([]string(nil), gr.syms[len(prefix):]...),
+					origins: gr.origins,
+				})
+			}
+			out = append(out, factored)
+			queue = append(queue, tail)
+		}
+		w.prods[nt] = out
+	}
+}
+
+func commonPrefix(rs []*rule) []string {
+	prefix := rs[0].syms
+	for _, r := range rs[1:] {
+		i := 0
+		for i < len(prefix) && i < len(r.syms) && prefix[i] == r.syms[i] {
+			i++
+		}
+		prefix = prefix[:i]
+	}
+	return prefix
+}
+
+// RemoveEpsilon removes empty productions. Every production that uses a
+// nullable non-terminal is replaced by the productions with and without that
+// non-terminal. If the start symbol is nullable, it keeps an empty production;
+// if it also appears on the right side of a production, a new start symbol is
+// added that derives either the old start symbol or nothing.
+func RemoveEpsilon(g parlex.Grammar) (*Grammar, Mapping) {
+	w := load(g)
+	w.removeEpsilon()
+	return w.grammar()
+}
+
+func (w *work) removeEpsilon() {
+	null := w.nullable()
+	start := w.start()
+	var startEmpty []Origin
+	for _, r := range w.prods[start] {
+		if len(r.syms) == 0 {
+			startEmpty = mergeOrigins(startEmpty, r.origins)
+		}
+	}
+
+	for _, nt := range w.order {
+		var out []*rule
+		for _, r := range w.prods[nt] {
+			for _, syms := range expandNullable(r.syms, null) {
+				if len(syms) > 0 {
+					out = add(out, &rule{syms: syms, origins: r.origins})
+				}
+			}
+		}
+		w.prods[nt] = out
+	}
+
+	if !null[start] {
+		return
+	}
+	if w.usesOnRHS(start) {
+		w.newStart()
+		start = w.start()
+	}
+	w.prods[start] = append(w.prods[start], &rule{origins: startEmpty})
+}
+
+// expandNullable returns every version of syms with and without each of the
+// nullable symbols.
+func expandNullable(syms []string, null map[string]bool) [][]string {
+	out := [][]string{nil}
+	for _, s := range syms {
+		ln := len(out)
+		for i := 0; i < ln; i++ {
+			if null[s] {
+				out = append(out, out[i])
+			}
+			out[i] = append(append([]string(nil), out[i]...), s)
+		}
+	}
+	return out
+}
+
+// RemoveUnit removes productions that are a single non-terminal, A -> B, by
+// giving A all the productions of B.
+func RemoveUnit(g parlex.Grammar) (*Grammar, Mapping) {
+	w := load(g)
+	w.removeUnit()
+	return w.grammar()
+}
+
+func (w *work) isUnit(r *rule) bool {
+	return len(r.syms) == 1 && w.nts[r.syms[0]]
+}
+
+func (w *work) removeUnit() {
+	out := make(map[string][]*rule, len(w.order))
+	for _, nt := range w.order {
+		type reach struct {
+			nt      string
+			origins []Origin
+		}
+		seen := map[string]bool{nt: true}
+		queue := []reach{{nt: nt}}
+		var rs []*rule
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			for _, r := range w.prods[cur.nt] {
+				origins := mergeOrigins(cur.origins, r.origins)
+				if !w.isUnit(r) {
+					rs = add(rs, &rule{syms: r.syms, origins: origins})
+					continue
+				}
+				if u := r.syms[0]; !seen[u] {
+					seen[u] = true
+					queue = append(queue, reach{u, origins})
+				}
+			}
+		}
+		out[nt] = rs
+	}
+	w.prods = out
+}
+
+// RemoveUseless removes non-terminals that cannot derive a string of terminals
+// and then the non-terminals that cannot be reached from the start symbol,
+// along with every production that uses them.
+func RemoveUseless(g parlex.Grammar) (*Grammar, Mapping) {
+	w := load(g)
+	w.removeUseless()
+	return w.grammar()
+}
+
+func (w *work) removeUseless() {
+	gen := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, nt := range w.order {
+			if gen[nt] {
+				continue
+			}
+			for _, r := range w.prods[nt] {
+				if w.generates(r, gen) {
+					gen[nt] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	for _, nt := range w.order {
+		var out []*rule
+		for _, r := range w.prods[nt] {
+			if gen[nt] && w.generates(r, gen) {
+				out = append(out, r)
+			}
+		}
+		w.prods[nt] = out
+	}
+
+	reached := map[string]bool{}
+	if start := w.start(); gen[start] {
+		reached[start] = true
+		queue := []string{start}
+		for len(queue) > 0 {
+			nt := queue[0]
+			queue = queue[1:]
+			for _, r := range w.prods[nt] {
+				for _, s := range r.syms {
+					if w.nts[s] && !reached[s] {
+						reached[s] = true
+						queue = append(queue, s)
+					}
+				}
+			}
+		}
+	}
+	var order []string
+	for _, nt := range w.order {
+		if reached[nt] {
+			order = append(order, nt)
+		} else {
+			delete(w.prods, nt)
+		}
+	}
+	w.order = order
+}
+
+func (w *work) generates(r *rule, gen map[string]bool) bool {
+	for _, s := range r.syms {
+		if w.nts[s] && !gen[s] {
+			return false
+		}
+	}
+	return true
+}
+
+// CNF converts a grammar to Chomsky normal form. Every production is either two
+// non-terminals or a single terminal. The start symbol may have an empty
+// production, in which case it does not appear on the right side of any
+// production. New non-terminals are named by adding ' to the symbol they
+// replace.
+func CNF(g parlex.Grammar) (*Grammar, Mapping) {
+	w := load(g)
+	w.cnf()
+	return w.grammar()
+}
+
+func (w *work) cnf() {
+	if w.start() == "" {
+		return
+	}
+	if w.usesOnRHS(w.start()) {
+		w.newStart()
+	}
+
+	// replace terminals in long productions
+	terms := make(map[string]string)
+	for _, nt := range append([]string(nil), w.order...) {
+		for _, r := range w.prods[nt] {
+			if len(r.syms) < 2 {
+				continue
+			}
+			for i, s := range r.syms {
+				if w.nts[s] {
+					continue
+				}
+				t, ok := terms[s]
+				if !ok {
+					t = w.fresh(s)
+					terms[s] = t
+					w.nts[t] = true
+					w.order = append(w.order, t)
+					w.prods[t] = []*rule{{syms: []string{s}}}
+				}
+				r.syms[i] = t
+			}
+		}
+	}
+
+	// split long productions
+	for _, nt := range append([]string(nil), w.order...) {
+		last := nt
+		for _, r := range w.prods[nt] {
+			cur := r
+			for len(cur.syms) > 2 {
+				n := w.fresh(nt)
+				w.addNT(n, last)
+				last = n
+				rest := &rule{
+					syms:    cur.syms[1:],
+					origins: cur.origins,
+				}
+				cur.syms = []string{cur.syms[0], n}
+				w.prods[n] = []*rule{rest}
+				cur = rest
+			}
+		}
+	}
+
+	w.removeEpsilon()
+	w.removeUnit()
+	w.removeUseless()
+}
+
+// GNF converts a grammar to Greibach normal form. Every production is a
+// terminal followed by zero or more non-terminals. The start symbol may have an
+// empty production, in which case it does not appear on the right side of any
+// production. The grammar is first converted to CNF.
+func GNF(g parlex.Grammar) (*Grammar, Mapping) {
+	w := load(g)
+	w.cnf()
+	w.gnf()
+	return w.grammar()
+}
+
+func (w *work) substitute(r *rule, with []*rule) []*rule {
+	out := make([]*rule, len(with))
+	for i, r2 := range with {
+		out[i] = &rule{
+			syms:    append(append([]string(nil), r2.syms...), r.syms[1:]...),
+			origins: mergeOrigins(r.origins, r2.origins),
+		}
+	}
+	return out
+}
+
+func (w *work) gnf() {
+	order := append([]string(nil), w.order...)
+	idx := make(map[string]int, len(order))
+	for i, nt := range order {
+		idx[nt] = i
+	}
+	var tails []string
+
+	// make every production of the ith non-terminal start with a terminal or a
+	// non-terminal with a higher index
+	for i, nt := range order {
+		for {
+			var out []*rule
+			changed := false
+			for _, r := range w.prods[nt] {
+				if j, ok := idx[r.first()]; ok && j < i {
+					for _, r2 := range w.substitute(r, w.prods[order[j]]) {
+						out = add(out, r2)
+					}
+					changed = true
+				} else {
+					out = add(out, r)
+				}
+			}
+			w.prods[nt] = out
+			if !changed {
+				break
+			}
+		}
+
+		var alphas, betas []*rule
+		for _, r := range w.prods[nt] {
+			if r.first() == nt {
+				alphas = append(alphas, &rule{syms: r.syms[1:], origins: r.origins})
+			} else {
+				betas = append(betas, r)
+			}
+		}
+		if len(alphas) == 0 {
+			continue
+		}
+		tail := w.fresh(nt)
+		w.addNT(tail, nt)
+		tails = append(tails, tail)
+		out := betas
+		for _, b := range betas {
+			if len(b.syms) > 0 {
+				out = add(out, &rule{syms: append(append([]string(nil), b.syms...), tail), origins: b.origins})
+			}
+		}
+		w.prods[nt] = out
+		var tailRules []*rule
+		for _, a := range alphas {
+			tailRules = add(tailRules, a)
+			tailRules = add(tailRules, &rule{syms: append(append([]string(nil), a.syms...), tail), origins: a.origins})
+		}
+		w.prods[tail] = tailRules
+	}
+
+	// substitute back down so every production starts with a terminal
+	resolve := func(nt string) {
+		for {
+			var out []*rule
+			changed := false
+			for _, r := range w.prods[nt] {
+				if f := r.first(); f != nt && w.nts[f] {
+					for _, r2 := range w.substitute(r, w.prods[f]) {
+						out = add(out, r2)
+					}
+					changed = true
+				} else {
+					out = add(out, r)
+				}
+			}
+			w.prods[nt] = out
+			if !changed {
+				return
+			}
+		}
+	}
+	for i := len(order) - 1; i >= 0; i-- {
+		resolve(order[i])
+	}
+	for _, t := range tails {
+		resolve(t)
+	}
+	w.removeUseless()
+}
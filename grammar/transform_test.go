@@ -0,0 +1,209 @@
+package grammar
+
+import (
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/grammar/generate"
+	"github.com/adamcolton/parlex/parser/packrat"
+	"github.com/stretchr/testify/assert"
+)
+
+const testGrammar = `
+  S -> A S B
+    -> a
+  A -> A a
+    -> b
+    ->
+  B -> S
+    -> c
+    -> D
+  D -> d D
+`
+
+func mustGrammar(t *testing.T, s string) *Grammar {
+	g, err := New(s)
+	assert.NoError(t, err)
+	return g
+}
+
+func TestLeftFactor(t *testing.T) {
+	g := mustGrammar(t, `
+    A -> a b C
+      -> a b D
+      -> a
+      -> e
+  `)
+	out, m := LeftFactor(g)
+	expected := mustGrammar(t, `
+    A   -> a A'
+        -> e
+    A'  -> b A''
+        ->
+    A'' -> C
+        -> D
+  `)
+	assert.Equal(t, expected.String(), out.String())
+	assert.Equal(t, []Origin{{"A", 0}, {"A", 1}, {"A", 2}}, m.Origins("A", 0))
+	assert.Equal(t, []Origin{{"A", 3}}, m.Origins("A", 1))
+	assert.Equal(t, []Origin{{"A", 2}}, m.Origins("A'", 1))
+	assert.Equal(t, []Origin{{"A", 1}}, m.Origins("A''", 1))
+}
+
+func TestRemoveEpsilon(t *testing.T) {
+	out, m := RemoveEpsilon(mustGrammar(t, testGrammar))
+	expected := mustGrammar(t, `
+    S -> A S B
+      -> S B
+      -> a
+    A -> A a
+      -> a
+      -> b
+    B -> S
+      -> c
+      -> D
+    D -> d D
+  `)
+	assert.Equal(t, expected.String(), out.String())
+	assert.Equal(t, []Origin{{"S", 0}}, m.Origins("S", 1))
+	assert.Equal(t, []Origin{{"A", 0}}, m.Origins("A", 1))
+
+	out, m = RemoveEpsilon(mustGrammar(t, `
+    S -> ( S )
+      ->
+  `))
+	expected = mustGrammar(t, `
+    S' -> S
+       ->
+    S  -> ( S )
+       -> ( )
+  `)
+	assert.Equal(t, expected.String(), out.String())
+	assert.Nil(t, m.Origins("S'", 0))
+	assert.Equal(t, []Origin{{"S", 1}}, m.Origins("S'", 1))
+}
+
+func TestRemoveUnit(t *testing.T) {
+	out, m := RemoveUnit(mustGrammar(t, testGrammar))
+	expected := mustGrammar(t, `
+    S -> A S B
+      -> a
+    A -> A a
+      -> b
+      ->
+    B -> c
+      -> A S B
+      -> a
+      -> d D
+    D -> d D
+  `)
+	assert.Equal(t, expected.String(), out.String())
+	assert.Equal(t, []Origin{{"B", 0}, {"S", 0}}, m.Origins("B", 1))
+	assert.Equal(t, []Origin{{"B", 2}, {"D", 0}}, m.Origins("B", 3))
+}
+
+func TestRemoveUseless(t *testing.T) {
+	out, m := RemoveUseless(mustGrammar(t, testGrammar+`
+    E -> e
+  `))
+	expected := mustGrammar(t, `
+    S -> A S B
+      -> a
+    A -> A a
+      -> b
+      ->
+    B -> S
+      -> c
+  `)
+	assert.Equal(t, expected.String(), out.String())
+	assert.Equal(t, []Origin{{"B", 1}}, m.Origins("B", 1))
+}
+
+func TestCNF(t *testing.T) {
+	g := mustGrammar(t, testGrammar)
+	out, _ := CNF(g)
+	start := out.NonTerminals()[0]
+	forEachProd(out, func(nt parlex.Symbol, prod parlex.Production) {
+		switch prod.Symbols() {
+		case 0:
+			assert.Equal(t, start, nt)
+		case 1:
+			assert.Nil(t, out.Productions(prod.Symbol(0)), prod)
+		case 2:
+			assert.NotNil(t, out.Productions(prod.Symbol(0)), prod)
+			assert.NotNil(t, out.Productions(prod.Symbol(1)), prod)
+		default:
+			t.Error(nt, prod)
+		}
+	})
+	assertSameLanguage(t, g, out)
+}
+
+func TestGNF(t *testing.T) {
+	g := mustGrammar(t, testGrammar)
+	out, m := GNF(g)
+	start := out.NonTerminals()[0]
+	forEachProd(out, func(nt parlex.Symbol, prod parlex.Production) {
+		if prod.Symbols() == 0 {
+			assert.Equal(t, start, nt)
+			return
+		}
+		assert.Nil(t, out.Productions(prod.Symbol(0)), prod)
+		for i := 1; i < prod.Symbols(); i++ {
+			assert.NotNil(t, out.Productions(prod.Symbol(i)), prod)
+		}
+	})
+	assertSameLanguage(t, g, out)
+	for k, os := range m {
+		assert.NotEmpty(t, os, k)
+	}
+}
+
+func TestTransformsPreserveLanguage(t *testing.T) {
+	g := mustGrammar(t, testGrammar)
+	for name, fn := range map[string]func(parlex.Grammar) (*Grammar, Mapping){
+		"LeftFactor":    LeftFactor,
+		"RemoveEpsilon": RemoveEpsilon,
+		"RemoveUnit":    RemoveUnit,
+		"RemoveUseless": RemoveUseless,
+	} {
+		t.Run(name, func(t *testing.T) {
+			out, _ := fn(g)
+			assertSameLanguage(t, g, out)
+		})
+	}
+}
+
+func TestMappingThen(t *testing.T) {
+	g := mustGrammar(t, testGrammar)
+	useful, m1 := RemoveUseless(g)
+	_, m2 := RemoveUnit(useful)
+	m := m1.Then(m2)
+	assert.Equal(t, []Origin{{"B", 0}, {"S", 0}}, m.Origins("B", 1))
+	assert.Equal(t, m2.Origins("S", 0), m.Origins("S", 0))
+	assert.Len(t, m, len(m2))
+}
+
+func forEachProd(g parlex.Grammar, fn func(nt parlex.Symbol, prod parlex.Production)) {
+	for _, nt := range g.NonTerminals() {
+		for i := g.Productions(nt).Iter(); i.Next(); {
+			fn(nt, i.Production)
+		}
+	}
+}
+
+// assertSameLanguage generates sentences from each grammar and checks that the
+// other grammar accepts them.
+func assertSameLanguage(t *testing.T, a, b parlex.Grammar) {
+	for _, pair := range [][2]parlex.Grammar{{a, b}, {b, a}} {
+		gen := generate.Must(pair[0])
+		gen.MaxDepth = 6
+		p := packrat.New(pair[1])
+		for i := 0; i < 50; i++ {
+			lxms := gen.Lexemes()
+			if !assert.NotNil(t, p.Parse(lxms), generate.Text(lxms, " ")) {
+				return
+			}
+		}
+	}
+}
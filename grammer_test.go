@@ -52,3 +52,37 @@ func TestMustGrammar(t *testing.T) {
 	}()
 	MustGrammar(g, testErr)
 }
+
+type precGrammar struct {
+	testGrammar
+	levels map[string]int
+}
+
+func (pg *precGrammar) Precedence(operator string) (int, Assoc, bool) {
+	level, ok := pg.levels[operator]
+	return level, LeftAssoc, ok
+}
+
+func TestOperatorPrecedence(t *testing.T) {
+	g := &precGrammar{
+		levels: map[string]int{
+			"add": 1,
+			"*":   2,
+		},
+	}
+
+	level, assoc, ok := OperatorPrecedence(g, &lx{k: "add", v: "+"})
+	assert.True(t, ok)
+	assert.Equal(t, 1, level)
+	assert.Equal(t, LeftAssoc, assoc)
+
+	level, _, ok = OperatorPrecedence(g, &lx{k: "mul", v: "*"})
+	assert.True(t, ok)
+	assert.Equal(t, 2, level)
+
+	_, _, ok = OperatorPrecedence(g, &lx{k: "mul", v: "/"})
+	assert.False(t, ok)
+
+	_, _, ok = OperatorPrecedence(&g.testGrammar, &lx{k: "add", v: "+"})
+	assert.False(t, ok)
+}
@@ -49,6 +49,62 @@ func LexemeList(ls []Lexeme) string {
 	return strings.Join(strs, "\n")
 }
 
+// End returns the position immediately after the lexeme. If the lexeme does
+// not fulfill Spanner or does not know it's end, ok will be false.
+func End(l Lexeme) (line, col int, ok bool) {
+	s, isSpanner := l.(Spanner)
+	if !isSpanner {
+		return 0, 0, false
+	}
+	line, col = s.End()
+	return line, col, col > 0
+}
+
+// OperatorPrecedence looks up the precedence of an operator lexeme in a
+// grammar that fulfills Precedencer. The kind of the lexeme is checked first,
+// then it's value, so a declaration can name either a terminal or the text of
+// the operator.
+func OperatorPrecedence(g Grammar, op Lexeme) (level int, assoc Assoc, ok bool) {
+	p, isPrecedencer := g.(Precedencer)
+	if !isPrecedencer || op == nil {
+		return 0, NonAssoc, false
+	}
+	if level, assoc, ok = p.Precedence(op.Kind().String()); ok {
+		return
+	}
+	return p.Precedence(op.Value())
+}
+
+// Source reconstructs the original text from lexemes. Any Lexeme that fulfills
+// Trivia contributes it's leading trivia, source and trailing trivia. Any
+// other Lexeme contributes it's value.
+func Source(ls ...Lexeme) string {
+	var strs []string
+	for _, l := range ls {
+		strs = appendSource(strs, l)
+	}
+	return strings.Join(strs, "")
+}
+
+func appendSource(strs []string, l Lexeme) []string {
+	t, ok := l.(Trivia)
+	if !ok {
+		return append(strs, l.Value())
+	}
+	src, ok := t.Source()
+	if !ok {
+		src = l.Value()
+	}
+	for _, lead := range t.Leading() {
+		strs = appendSource(strs, lead)
+	}
+	strs = append(strs, src)
+	for _, trail := range t.Trailing() {
+		strs = appendSource(strs, trail)
+	}
+	return strs
+}
+
 // MustParser consumes the error from a parser constructor and panics if it is
 // not nil.
 func MustParser(p Parser, err error) Parser {
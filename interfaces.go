@@ -29,6 +29,24 @@ type Lexeme interface {
 	Pos() (line int, col int)
 }
 
+// Spanner is optionally fulfilled by a Lexeme that knows where it ends in the
+// original string. End returns the (line, column) immediately after the
+// lexeme. Columns start at 1, so a column less than 1 indicates that the end is
+// not known.
+type Spanner interface {
+	End() (line int, col int)
+}
+
+// Trivia is optionally fulfilled by a Lexeme when the lexer retained the text
+// that it would otherwise discard, such as whitespace and comments. Source
+// returns the exact text that the lexeme was lexed from; if that is not known
+// the bool will be false.
+type Trivia interface {
+	Leading() []Lexeme
+	Trailing() []Lexeme
+	Source() (string, bool)
+}
+
 // Lexer is fulfilled by a type that can convert a string into a slice of
 // Lexemes.
 type Lexer interface {
@@ -49,6 +67,13 @@ type Parser interface {
 	Parse([]Lexeme) ParseNode
 }
 
+// PrefixParser parses the longest prefix of the lexemes that it can. It
+// returns the ParseNode and the number of lexemes used. If nothing can be
+// parsed, the ParseNode will be nil.
+type PrefixParser interface {
+	ParsePrefix([]Lexeme) (ParseNode, int)
+}
+
 // ParserConstructor is a function that takes a Grammar and returns a Parser
 type ParserConstructor func(Grammar) (Parser, error)
 
@@ -58,6 +83,44 @@ type Grammar interface {
 	NonTerminals() []Symbol // The first NonTerminal should be the start symbol
 }
 
+// Assoc is the associativity of an operator.
+type Assoc byte
+
+// Assocs
+const (
+	NonAssoc Assoc = iota
+	LeftAssoc
+	RightAssoc
+)
+
+func (a Assoc) String() string {
+	switch a {
+	case LeftAssoc:
+		return "left"
+	case RightAssoc:
+		return "right"
+	}
+	return "nonassoc"
+}
+
+// Precedencer is optionally fulfilled by a Grammar that declares the
+// precedence and associativity of operators. Operators with a higher level
+// bind tighter. Parsers use it to resolve ambiguous productions like
+// E -> E op E.
+type Precedencer interface {
+	Precedence(operator string) (level int, assoc Assoc, ok bool)
+}
+
+// TerminalMatcher is optionally fulfilled by a Grammar with terminals that are
+// matched by something other than the kind of a single lexeme, like the
+// literals and character classes of a scannerless grammar. MatchTerminal
+// returns the number of lexemes the terminal matches at the start of lexemes.
+// If it returns false, parsers fall back to comparing the terminal to the kind
+// of the lexeme.
+type TerminalMatcher interface {
+	MatchTerminal(terminal Symbol, lexemes []Lexeme) (int, bool)
+}
+
 // Reducer is used to reduce a ParseTree to something more useful, generally
 // clearing away symbols that are now represeneted by the tree structure.
 type Reducer interface {
@@ -10,11 +10,26 @@ type symbol string
 
 func (s symbol) String() string { return string(s) }
 
-// Lexeme is a concrete implementation of parlex.Lexeme
+// Lexeme is a concrete implementation of parlex.Lexeme and parlex.Spanner. If T
+// is not nil, the Lexeme also fulfills parlex.Trivia. L and C are the line and
+// column where the lexeme starts, EL and EC are the line and column immediately
+// after it ends. Columns start at 1 so a column of 0 means the position is not
+// known.
 type Lexeme struct {
-	K    parlex.Symbol
-	V    string
-	L, C int
+	K      parlex.Symbol
+	V      string
+	L, C   int
+	EL, EC int
+	T      *Trivia
+}
+
+// Trivia holds the text around a lexeme that a lexer would otherwise discard.
+// Src is the exact text the lexeme was lexed from, which may differ from the
+// value. Lexemes that were not lexed from the input, like those inserted at
+// the start or end, should have an empty Src.
+type Trivia struct {
+	Src         string
+	Lead, Trail []parlex.Lexeme
 }
 
 // New returns a new Lexeme. Line is initially set to -1 to indicate the the
@@ -53,9 +68,53 @@ func (l *Lexeme) At(line, col int) *Lexeme {
 	return l
 }
 
-// Copy a parlex.Lexeme to *Lexeme
+// To sets the line and column immediately after the end of the lexeme and
+// returns the Lexeme.
+func (l *Lexeme) To(line, col int) *Lexeme {
+	l.EL, l.EC = line, col
+	return l
+}
+
+// Span widens the position of the lexeme so that it covers each of the other
+// lexemes that have a known position and returns the Lexeme. This is used when
+// nodes are merged during a reduction.
+func (l *Lexeme) Span(others ...parlex.Lexeme) *Lexeme {
+	for _, o := range others {
+		if o == nil {
+			continue
+		}
+		if ol, oc := o.Pos(); oc > 0 && (l.C < 1 || before(ol, oc, l.L, l.C)) {
+			l.L, l.C = ol, oc
+		}
+		if el, ec, ok := parlex.End(o); ok && (l.EC < 1 || before(l.EL, l.EC, el, ec)) {
+			l.EL, l.EC = el, ec
+		}
+	}
+	return l
+}
+
+func before(l1, c1, l2, c2 int) bool {
+	return l1 < l2 || (l1 == l2 && c1 < c2)
+}
+
+// Copy a parlex.Lexeme to *Lexeme. The position and end are copied. If the
+// Lexeme fulfills parlex.Trivia and knows it's source, the trivia is also
+// copied.
 func Copy(l parlex.Lexeme) *Lexeme {
-	return New(l.Kind()).Set(l.Value()).At(l.Pos())
+	cp := New(l.Kind()).Set(l.Value()).At(l.Pos())
+	if el, ec, ok := parlex.End(l); ok {
+		cp.To(el, ec)
+	}
+	if t, ok := l.(parlex.Trivia); ok {
+		if src, ok := t.Source(); ok {
+			cp.T = &Trivia{
+				Src:   src,
+				Lead:  t.Leading(),
+				Trail: t.Trailing(),
+			}
+		}
+	}
+	return cp
 }
 
 // Kind returns the token indicating what kind of lexeme this is
@@ -68,6 +127,38 @@ func (l *Lexeme) Value() string { return l.V }
 // the original string.
 func (l *Lexeme) Pos() (int, int) { return l.L, l.C }
 
+// End returns the position as (line, column) immediately after the lexeme in
+// the original string. It is part of the parlex.Spanner interface.
+func (l *Lexeme) End() (int, int) { return l.EL, l.EC }
+
+// Leading returns any discarded lexemes that came before this lexeme. It is
+// part of the parlex.Trivia interface.
+func (l *Lexeme) Leading() []parlex.Lexeme {
+	if l.T == nil {
+		return nil
+	}
+	return l.T.Lead
+}
+
+// Trailing returns any discarded lexemes that came after this lexeme. It is
+// part of the parlex.Trivia interface.
+func (l *Lexeme) Trailing() []parlex.Lexeme {
+	if l.T == nil {
+		return nil
+	}
+	return l.T.Trail
+}
+
+// Source returns the exact text the lexeme was lexed from. If the lexeme does
+// not have Trivia, the bool will be false. It is part of the parlex.Trivia
+// interface.
+func (l *Lexeme) Source() (string, bool) {
+	if l.T == nil {
+		return "", false
+	}
+	return l.T.Src, true
+}
+
 // String returns a formatted representation of the lexeme.
 func (l *Lexeme) String() string {
 	pos := ""
@@ -0,0 +1,60 @@
+package lexeme
+
+import (
+	"strings"
+
+	"github.com/adamcolton/parlex"
+)
+
+// TriviaBuilder is used by a lexer to attach the lexemes it discards to the
+// lexemes it keeps. Discarded lexemes following a kept lexeme on the same line,
+// up to and including the first one containing a newline, are trailing trivia.
+// Any others are leading trivia of the next kept lexeme.
+type TriviaBuilder struct {
+	last    *Lexeme
+	pending []parlex.Lexeme
+	closed  bool
+}
+
+// Keep records that lx was kept and was lexed from src.
+func (tb *TriviaBuilder) Keep(lx *Lexeme, src string) {
+	lx.T = &Trivia{
+		Src:  src,
+		Lead: tb.pending,
+	}
+	tb.pending = nil
+	tb.last = lx
+	tb.closed = false
+}
+
+// Synthetic records that lx was not lexed from the input. It will not collect
+// any trivia.
+func (tb *TriviaBuilder) Synthetic(lx *Lexeme) {
+	lx.T = &Trivia{}
+}
+
+// Discard records that lx, which was lexed from src, was discarded.
+func (tb *TriviaBuilder) Discard(lx *Lexeme, src string) {
+	lx.T = &Trivia{Src: src}
+	if tb.last != nil && !tb.closed {
+		tb.last.T.Trail = append(tb.last.T.Trail, lx)
+		tb.closed = strings.Contains(src, "\n")
+		return
+	}
+	tb.pending = append(tb.pending, lx)
+}
+
+// Close is called at the end of the input. Any pending trivia is attached as
+// trailing trivia of the last lexeme that was kept. If no lexeme was kept, the
+// trivia is attached to end, which may be nil.
+func (tb *TriviaBuilder) Close(end *Lexeme) {
+	if len(tb.pending) == 0 {
+		return
+	}
+	if tb.last != nil {
+		tb.last.T.Trail = append(tb.last.T.Trail, tb.pending...)
+	} else if end != nil {
+		end.T.Lead = append(end.T.Lead, tb.pending...)
+	}
+	tb.pending = nil
+}
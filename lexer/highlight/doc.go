@@ -0,0 +1,11 @@
+// Package highlight generates syntax highlighting definitions from lexer
+// definitions. A lexer is converted to a Definition, then combined with Scopes
+// that map lexeme kinds to highlighting scopes to produce either a TextMate
+// grammar or tree-sitter style highlight queries.
+//
+// Sub-lexers in a stacklexer become TextMate repository entries. A rule that
+// pushes a sub-lexer becomes a begin/end pattern where the end is formed from
+// the pop rules of the pushed sub-lexer. An end pattern only closes it's own
+// begin pattern, so rules that pop more than one sub-lexer, like ^^, cannot be
+// converted and TextMate returns an error for them.
+package highlight
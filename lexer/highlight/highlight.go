@@ -0,0 +1,136 @@
+package highlight
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/adamcolton/parlex/lexer/simplelexer"
+	"github.com/adamcolton/parlex/lexer/stacklexer"
+)
+
+// Rule is a lexer rule reduced to what is needed for highlighting.
+type Rule struct {
+	Kind    string
+	Re      *regexp.Regexp
+	Discard bool
+	Push    string
+	Pop     int
+}
+
+// Mode is a named set of rules that are active together. A simplelexer has a
+// single mode, a stacklexer has one mode per sub-lexer.
+type Mode struct {
+	Name  string
+	Rules []Rule
+}
+
+// Definition is the set of modes that make up a lexer. The first mode is the
+// start mode.
+type Definition []Mode
+
+// FromSimple creates a Definition from a simplelexer. The single mode will be
+// named "main".
+func FromSimple(lxr *simplelexer.Lexer) Definition {
+	rules := lxr.Rules()
+	m := Mode{
+		Name:  "main",
+		Rules: make([]Rule, len(rules)),
+	}
+	for i, r := range rules {
+		m.Rules[i] = Rule{
+			Kind:    r.Kind.String(),
+			Re:      r.Re,
+			Discard: r.Discard,
+		}
+	}
+	return Definition{m}
+}
+
+// FromStack creates a Definition from a stacklexer with one mode per
+// sub-lexer.
+func FromStack(lxr *stacklexer.StackLexer) Definition {
+	names := lxr.Lexers()
+	d := make(Definition, len(names))
+	for i, name := range names {
+		rules := lxr.Rules(name)
+		d[i] = Mode{
+			Name:  name,
+			Rules: make([]Rule, len(rules)),
+		}
+		for j, r := range rules {
+			d[i].Rules[j] = Rule{
+				Kind:    r.Kind.String(),
+				Re:      r.Re,
+				Discard: r.Discard,
+				Push:    r.Push,
+				Pop:     r.Pop,
+			}
+		}
+	}
+	return d
+}
+
+// Mode returns the mode by name. If there is no mode with that name, nil is
+// returned.
+func (d Definition) Mode(name string) *Mode {
+	for i := range d {
+		if d[i].Name == name {
+			return &d[i]
+		}
+	}
+	return nil
+}
+
+// Kinds returns every kind in the definition in the order they first appear.
+func (d Definition) Kinds() []string {
+	seen := make(map[string]bool)
+	var kinds []string
+	for _, m := range d {
+		for _, r := range m.Rules {
+			if !seen[r.Kind] {
+				seen[r.Kind] = true
+				kinds = append(kinds, r.Kind)
+			}
+		}
+	}
+	return kinds
+}
+
+// Scopes maps lexeme kinds to highlighting scopes such as
+// "constant.numeric". A kind with no scope is not highlighted.
+type Scopes map[string]string
+
+// ErrBadScope is returned when a line in a scope definition cannot be parsed.
+var ErrBadScope = errors.New("Bad Scope Definition")
+
+var scopeLine = regexp.MustCompile(`^\s*(\S+)\s+(\S+)\s*$`)
+
+// ParseScopes parses a scope mapping. Each line maps a kind to a scope
+//   number  constant.numeric
+//   string  string.quoted.double
+// Blank lines and lines starting with // are ignored.
+func ParseScopes(str string) (Scopes, error) {
+	s := make(Scopes)
+	for _, line := range strings.Split(str, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		m := scopeLine.FindStringSubmatch(line)
+		if m == nil {
+			return nil, ErrBadScope
+		}
+		s[m[1]] = m[2]
+	}
+	return s, nil
+}
+
+// MustScopes calls ParseScopes and panics if there is an error.
+func MustScopes(str string) Scopes {
+	s, err := ParseScopes(str)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
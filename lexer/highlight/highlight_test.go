@@ -0,0 +1,120 @@
+package highlight
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/adamcolton/parlex/lexer/simplelexer"
+	"github.com/adamcolton/parlex/lexer/stacklexer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScopes(t *testing.T) {
+	s, err := ParseScopes(`
+    // comments are ignored
+    number  constant.numeric
+    string  string.quoted.double
+  `)
+	assert.NoError(t, err)
+	assert.Equal(t, Scopes{
+		"number": "constant.numeric",
+		"string": "string.quoted.double",
+	}, s)
+
+	_, err = ParseScopes("number")
+	assert.Equal(t, ErrBadScope, err)
+}
+
+func TestSimpleTextMate(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    number /\d+/
+    op     /[\+\-]/
+    space  /\s+/ -
+  `)
+	assert.NoError(t, err)
+	d := FromSimple(lxr)
+	b, err := d.TextMate("Math", "source.math", MustScopes(`
+    number constant.numeric
+    op     keyword.operator
+  `))
+	assert.NoError(t, err)
+
+	var g tmGrammar
+	assert.NoError(t, json.Unmarshal(b, &g))
+	assert.Equal(t, "source.math", g.ScopeName)
+	assert.Equal(t, "#main", g.Patterns[0].Include)
+	ps := g.Repository["main"].Patterns
+	if assert.Len(t, ps, 2) {
+		assert.Equal(t, `\d+`, ps[0].Match)
+		assert.Equal(t, "constant.numeric", ps[0].Name)
+		assert.Equal(t, "keyword.operator", ps[1].Name)
+	}
+}
+
+func TestStackTextMate(t *testing.T) {
+	lxr, err := stacklexer.New(`
+    == main ==
+      quote /"/ str
+      word  /\w+/
+      space /\s+/ -
+    == str ==
+      close /"/ ^
+      esc   /\\./
+      chars /[^"\\]+/
+  `)
+	assert.NoError(t, err)
+	d := FromStack(lxr)
+	assert.Equal(t, []string{"main", "str"}, []string{d[0].Name, d[1].Name})
+
+	b, err := d.TextMate("Str", "source.str", MustScopes(`
+    quote punctuation.definition.string.begin
+    close punctuation.definition.string.end
+    esc   constant.character.escape
+    chars string.quoted.double
+  `))
+	assert.NoError(t, err)
+
+	var g tmGrammar
+	assert.NoError(t, json.Unmarshal(b, &g))
+	ps := g.Repository["main"].Patterns
+	if assert.Len(t, ps, 1) {
+		p := ps[0]
+		assert.Equal(t, `"`, p.Begin)
+		assert.Equal(t, `"`, p.End)
+		assert.Equal(t, "punctuation.definition.string.begin", p.BeginCaptures["0"].Name)
+		assert.Equal(t, "punctuation.definition.string.end", p.EndCaptures["0"].Name)
+		assert.Equal(t, "#str", p.Patterns[0].Include)
+	}
+	assert.Len(t, g.Repository["str"].Patterns, 2)
+
+	lxr, err = stacklexer.New(`
+    == main ==
+      lb    /\[/ list
+    == list ==
+      quote /"/ str
+      rb    /\]/ ^
+    == str ==
+      chars /[^"\]]+/
+      close /"/ ^
+      end   /\]/ ^^
+  `)
+	assert.NoError(t, err)
+	_, err = FromStack(lxr).TextMate("Str", "source.str", MustScopes(`
+    chars string.quoted.double
+  `))
+	assert.EqualError(t, err, "Rule end pops 2 lexers, TextMate can only pop 1")
+}
+
+func TestTreeSitter(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    number /\d+/
+    (      /\(/
+    space  /\s+/ -
+  `)
+	assert.NoError(t, err)
+	got := FromSimple(lxr).TreeSitter(Scopes{
+		"number": "number",
+		"(":      "punctuation.bracket",
+	})
+	assert.Equal(t, "(number) @number\n\"(\" @punctuation.bracket\n", got)
+}
@@ -0,0 +1,135 @@
+package highlight
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tmCapture struct {
+	Name string `json:"name"`
+}
+
+type tmPattern struct {
+	Name          string               `json:"name,omitempty"`
+	Match         string               `json:"match,omitempty"`
+	Begin         string               `json:"begin,omitempty"`
+	BeginCaptures map[string]tmCapture `json:"beginCaptures,omitempty"`
+	End           string               `json:"end,omitempty"`
+	EndCaptures   map[string]tmCapture `json:"endCaptures,omitempty"`
+	Include       string               `json:"include,omitempty"`
+	Patterns      []tmPattern          `json:"patterns,omitempty"`
+}
+
+type tmGrammar struct {
+	Name       string               `json:"name"`
+	ScopeName  string               `json:"scopeName"`
+	Patterns   []tmPattern          `json:"patterns"`
+	Repository map[string]tmPattern `json:"repository"`
+}
+
+// neverMatch is used as the end pattern of a sub-lexer that has no pop rules.
+const neverMatch = "(?!)"
+
+// TextMate produces a TextMate grammar as JSON. The name is the display name
+// of the language and scopeName is the root scope, for instance
+// "source.json". An end pattern can only close the begin pattern it belongs
+// to, so a rule that pops more than one sub-lexer returns an error.
+func (d Definition) TextMate(name, scopeName string, scopes Scopes) ([]byte, error) {
+	if len(d) == 0 {
+		return nil, fmt.Errorf("Definition has no modes")
+	}
+	g := tmGrammar{
+		Name:      name,
+		ScopeName: scopeName,
+		Patterns: []tmPattern{
+			{Include: "#" + d[0].Name},
+		},
+		Repository: make(map[string]tmPattern, len(d)),
+	}
+	for _, m := range d {
+		ps, err := d.tmPatterns(m, scopes)
+		if err != nil {
+			return nil, err
+		}
+		g.Repository[m.Name] = tmPattern{Patterns: ps}
+	}
+	return json.MarshalIndent(g, "", "  ")
+}
+
+func (d Definition) tmPatterns(m Mode, scopes Scopes) ([]tmPattern, error) {
+	var ps []tmPattern
+	for _, r := range m.Rules {
+		if r.Pop > 1 {
+			return nil, fmt.Errorf("Rule %s pops %d lexers, TextMate can only pop 1", r.Kind, r.Pop)
+		}
+		if r.Pop > 0 {
+			// pop rules are the end of the pattern that pushed this mode
+			continue
+		}
+		if r.Push == "" {
+			if scope, ok := scopes[r.Kind]; ok {
+				ps = append(ps, tmPattern{
+					Name:  scope,
+					Match: r.Re.String(),
+				})
+			}
+			continue
+		}
+
+		pushed := d.Mode(r.Push)
+		if pushed == nil {
+			return nil, fmt.Errorf("Rule %s pushes unknown lexer %s", r.Kind, r.Push)
+		}
+		p := tmPattern{
+			Begin:    r.Re.String(),
+			Patterns: []tmPattern{{Include: "#" + r.Push}},
+		}
+		if scope, ok := scopes[r.Kind]; ok {
+			p.BeginCaptures = map[string]tmCapture{"0": {scope}}
+		}
+		p.End, p.EndCaptures = tmEnd(pushed, scopes)
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+// tmEnd combines the pop rules of a mode into a single end pattern. When there
+// is more than one pop rule, each is wrapped in a group so that it can be
+// given it's own scope.
+func tmEnd(m *Mode, scopes Scopes) (string, map[string]tmCapture) {
+	var pops []Rule
+	for _, r := range m.Rules {
+		if r.Pop > 0 {
+			pops = append(pops, r)
+		}
+	}
+	if len(pops) == 0 {
+		return neverMatch, nil
+	}
+	captures := make(map[string]tmCapture)
+	if len(pops) == 1 {
+		if scope, ok := scopes[pops[0].Kind]; ok {
+			captures["0"] = tmCapture{scope}
+		}
+		return pops[0].Re.String(), nilIfEmpty(captures)
+	}
+	alts := make([]string, len(pops))
+	group := 1
+	for i, r := range pops {
+		alts[i] = "(" + r.Re.String() + ")"
+		if scope, ok := scopes[r.Kind]; ok {
+			captures[strconv.Itoa(group)] = tmCapture{scope}
+		}
+		group += 1 + r.Re.NumSubexp()
+	}
+	return strings.Join(alts, "|"), nilIfEmpty(captures)
+}
+
+func nilIfEmpty(captures map[string]tmCapture) map[string]tmCapture {
+	if len(captures) == 0 {
+		return nil
+	}
+	return captures
+}
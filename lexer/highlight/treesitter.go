@@ -0,0 +1,33 @@
+package highlight
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var tsIdentifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z_0-9]*$`)
+
+// TreeSitter produces tree-sitter style highlight queries with one line per
+// kind that has a scope. Kinds that are valid identifiers are treated as named
+// nodes, anything else is treated as an anonymous node.
+//   (number) @constant.numeric
+//   "(" @punctuation.bracket
+func (d Definition) TreeSitter(scopes Scopes) string {
+	var lines []string
+	for _, kind := range d.Kinds() {
+		scope, ok := scopes[kind]
+		if !ok {
+			continue
+		}
+		node := strconv.Quote(kind)
+		if tsIdentifier.MatchString(kind) {
+			node = "(" + kind + ")"
+		}
+		lines = append(lines, node+" @"+scope)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
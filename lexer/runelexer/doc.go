@@ -0,0 +1,13 @@
+// Package runelexer provides a lexer that makes one lexeme for every rune, so
+// that a grammar can be parsed without a separate lexer. Every lexeme has the
+// kind "char" and the rune as it's value.
+//
+// Terminals in a scannerless grammar are literals, like 'if' or "if",
+// character classes, like [a-z_] or [^0-9], and . which matches any rune.
+// ParseTerminal converts one of those to a Terminal that can be matched
+// against the lexemes. Scannerless wraps any grammar so that parsers that
+// support parlex.TerminalMatcher, like packrat, match it's terminals this way.
+//
+// Each rune ends up as a leaf in the parse tree. The tree.Collapse and
+// tree.ConcatChildValues reductions join them back into single values.
+package runelexer
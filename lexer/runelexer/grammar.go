@@ -0,0 +1,67 @@
+package runelexer
+
+import (
+	"github.com/adamcolton/parlex"
+)
+
+// Grammar wraps a grammar so that it's terminals can be literals, character
+// classes or "." and be matched against the lexemes from the Lexer. It
+// fulfills parlex.TerminalMatcher. Any other terminal is still matched by the
+// kind of the lexeme.
+type Grammar struct {
+	parlex.Grammar
+	Terminals
+}
+
+// Terminals maps the name of a terminal to the Terminal it is matched with. It
+// fulfills parlex.TerminalMatcher.
+type Terminals map[string]Terminal
+
+// Scannerless wraps a grammar so that it can be parsed from the lexemes of the
+// Lexer, without writing a lexer for it.
+//   grmr, rdcr := regexgram.Must(`
+//     Date -> [0-9]{4} '-' [0-9]{2} '-' [0-9]{2}
+//   `)
+//   rdcr = tree.Merge(rdcr, tree.Reducer{
+//     "Date": tree.Collapse("[0-9]"),
+//   })
+//   prsr := packrat.New(runelexer.Scannerless(grmr))
+//   pn := rdcr.Reduce(prsr.Parse(runelexer.New().Lex("2024-01-31")))
+func Scannerless(grmr parlex.Grammar) *Grammar {
+	g := &Grammar{
+		Grammar:   grmr,
+		Terminals: make(Terminals),
+	}
+	for _, nt := range grmr.NonTerminals() {
+		for i := grmr.Productions(nt).Iter(); i.Next(); {
+			for j := i.Iter(); j.Next(); {
+				name := j.Symbol.String()
+				if _, ok := g.Terminals[name]; ok || grmr.Productions(j.Symbol) != nil {
+					continue
+				}
+				if t, err := ParseTerminal(name); err == nil {
+					g.Terminals[name] = t
+				}
+			}
+		}
+	}
+	return g
+}
+
+// MatchTerminal fulfills parlex.TerminalMatcher.
+func (ts Terminals) MatchTerminal(terminal parlex.Symbol, lexemes []parlex.Lexeme) (int, bool) {
+	t, ok := ts[terminal.String()]
+	if !ok {
+		return 0, false
+	}
+	return t.Match(lexemes)
+}
+
+// Precedence passes through the precedence declared by the wrapped grammar, if
+// it fulfills parlex.Precedencer.
+func (g *Grammar) Precedence(operator string) (int, parlex.Assoc, bool) {
+	if p, ok := g.Grammar.(parlex.Precedencer); ok {
+		return p.Precedence(operator)
+	}
+	return 0, parlex.NonAssoc, false
+}
@@ -0,0 +1,37 @@
+package runelexer
+
+import (
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/lexeme"
+	"github.com/adamcolton/parlex/symbol/stringsymbol"
+)
+
+// Kind of every lexeme produced by the Lexer.
+const Kind = "char"
+
+var kind = stringsymbol.Symbol(Kind)
+
+// Lexer fulfills parlex.Lexer, it produces one lexeme per rune.
+type Lexer struct{}
+
+// New returns a Lexer
+func New() *Lexer {
+	return &Lexer{}
+}
+
+// Lex fulfills parlex.Lexer. Lines start at 0 and columns start at 1 and count
+// bytes, the same as simplelexer.
+func (*Lexer) Lex(str string) []parlex.Lexeme {
+	lxs := make([]parlex.Lexeme, 0, len(str))
+	line, col := 0, 1
+	for _, r := range str {
+		lx := lexeme.New(kind).Set(string(r)).At(line, col)
+		if r == '\n' {
+			line, col = line+1, 1
+		} else {
+			col += len(lx.V)
+		}
+		lxs = append(lxs, lx.To(line, col))
+	}
+	return lxs
+}
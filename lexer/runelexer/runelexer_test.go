@@ -0,0 +1,122 @@
+package runelexer
+
+import (
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/grammar/regexgram"
+	"github.com/adamcolton/parlex/parser/packrat"
+	"github.com/adamcolton/parlex/tree"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLex(t *testing.T) {
+	lxs := New().Lex("aé\nb")
+	assert.Equal(t, "[char: a, char: é, char: \n, char: b]", parlex.LexemeString(lxs...))
+
+	l, c := lxs[2].Pos()
+	assert.Equal(t, 0, l)
+	assert.Equal(t, 4, c)
+	l, c = lxs[3].Pos()
+	assert.Equal(t, 1, l)
+	assert.Equal(t, 1, c)
+}
+
+func TestTerminals(t *testing.T) {
+	lxs := New().Lex("if x")
+	tt := []struct {
+		terminal string
+		n        int
+		ok       bool
+	}{
+		{`'if'`, 2, true},
+		{`"if "`, 3, true},
+		{`'ix'`, 0, false},
+		{`''`, 0, true},
+		{`[a-z]`, 1, true},
+		{`[^a-z]`, 1, false},
+		{`[0-9_]`, 1, false},
+		{`[\]h-j]`, 1, true},
+		{`.`, 1, true},
+	}
+	for _, tc := range tt {
+		term, err := ParseTerminal(tc.terminal)
+		if !assert.NoError(t, err, tc.terminal) {
+			continue
+		}
+		n, ok := term.Match(lxs)
+		assert.Equal(t, tc.ok, ok, tc.terminal)
+		if tc.ok {
+			assert.Equal(t, tc.n, n, tc.terminal)
+		}
+	}
+
+	for _, bad := range []string{"id", "'if", "[]", "[z-a]", `'\'`} {
+		assert.False(t, IsTerminal(bad), bad)
+	}
+
+	n, ok := Literal("if x").Match(lxs[:2])
+	assert.False(t, ok)
+	assert.Equal(t, 0, n)
+	_, ok = Any{}.Match(nil)
+	assert.False(t, ok)
+}
+
+func TestScannerless(t *testing.T) {
+	grmr, rdcr := regexgram.Must(`
+    Date -> [0-9]{4} '-' [0-9]{2} '-' [0-9]{2}
+  `)
+	rdcr = tree.Merge(rdcr, tree.Reducer{
+		"Date": tree.Collapse("[0-9]"),
+	})
+	prsr := packrat.New(Scannerless(grmr))
+	lxr := New()
+
+	pn := rdcr.Reduce(prsr.Parse(lxr.Lex("2024-01-31")))
+	expected, err := tree.New(`
+    Date {
+      [0-9]: "2024"
+      '-': "-"
+      [0-9]: "01"
+      '-': "-"
+      [0-9]: "31"
+    }
+  `)
+	assert.NoError(t, err)
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, expected.String(), pn.(*tree.PN).String())
+	}
+	assert.Nil(t, prsr.Parse(lxr.Lex("2024-1-31")))
+
+	grmr, rdcr = regexgram.Must(`
+    URI    -> Scheme ':' '//' Host Path?
+    Scheme -> [a-z]+
+    Host   -> [a-z0-9]+ ('.' [a-z0-9]+)*
+    Path   -> ('/' [^/]*)+
+  `)
+	rdcr = tree.Merge(rdcr, tree.Reducer{
+		"URI":    tree.RemoveAll("':'", "'//'"),
+		"Scheme": tree.ConcatChildValues(""),
+		"Host":   tree.ConcatChildValues(""),
+		"Path":   tree.Collapse(),
+	})
+	prsr = packrat.New(Scannerless(grmr))
+
+	pn = rdcr.Reduce(prsr.Parse(lxr.Lex("https://example.com/a/bc")))
+	expected, err = tree.New(`
+    URI {
+      Scheme: "https"
+      Host: "example.com"
+      Path {
+        '/': "/"
+        [^/]: "a"
+        '/': "/"
+        [^/]: "bc"
+      }
+    }
+  `)
+	assert.NoError(t, err)
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, expected.String(), pn.(*tree.PN).String())
+	}
+}
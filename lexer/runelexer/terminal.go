@@ -0,0 +1,181 @@
+package runelexer
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/adamcolton/parlex"
+)
+
+// ErrBadTerminal is returned when a string is not a literal, a character class
+// or "."
+var ErrBadTerminal = errors.New("Bad terminal")
+
+// Terminal matches the start of a slice of lexemes.
+type Terminal interface {
+	// Match returns the number of lexemes matched and false if the terminal
+	// does not match.
+	Match(lexemes []parlex.Lexeme) (int, bool)
+}
+
+// Literal matches lexemes whose values, joined together, are the literal.
+type Literal string
+
+// Match fulfills Terminal.
+func (l Literal) Match(lexemes []parlex.Lexeme) (int, bool) {
+	str := string(l)
+	for i, lx := range lexemes {
+		if str == "" {
+			return i, true
+		}
+		v := lx.Value()
+		if v == "" || !strings.HasPrefix(str, v) {
+			return 0, false
+		}
+		str = str[len(v):]
+	}
+	if str != "" {
+		return 0, false
+	}
+	return len(lexemes), true
+}
+
+// Class matches a single lexeme whose value is one rune in the class.
+type Class struct {
+	Negate bool
+	// Ranges holds pairs of runes, each pair is an inclusive range.
+	Ranges []rune
+}
+
+// Match fulfills Terminal.
+func (c *Class) Match(lexemes []parlex.Lexeme) (int, bool) {
+	if len(lexemes) == 0 {
+		return 0, false
+	}
+	v := lexemes[0].Value()
+	r, size := utf8.DecodeRuneInString(v)
+	if size == 0 || size != len(v) {
+		return 0, false
+	}
+	in := false
+	for i := 0; i < len(c.Ranges) && !in; i += 2 {
+		in = r >= c.Ranges[i] && r <= c.Ranges[i+1]
+	}
+	if in == c.Negate {
+		return 0, false
+	}
+	return 1, true
+}
+
+// Any matches any single lexeme.
+type Any struct{}
+
+// Match fulfills Terminal.
+func (Any) Match(lexemes []parlex.Lexeme) (int, bool) {
+	if len(lexemes) == 0 {
+		return 0, false
+	}
+	return 1, true
+}
+
+// ParseTerminal converts a string to a Terminal. The string can be a literal
+// in single or double quotes, a character class in square brackets, or "."
+// for any rune. Literals and classes can use the escapes \n, \r, \t and a
+// backslash before any other rune to use that rune.
+//   ParseTerminal(`'if'`)
+//   ParseTerminal(`"\n"`)
+//   ParseTerminal(`[a-zA-Z_]`)
+//   ParseTerminal(`[^\]]`)
+func ParseTerminal(str string) (Terminal, error) {
+	if str == "." {
+		return Any{}, nil
+	}
+	if len(str) < 2 {
+		return nil, ErrBadTerminal
+	}
+	first, last := str[0], str[len(str)-1]
+	switch {
+	case (first == '\'' || first == '"') && last == first:
+		rs, ok := unescape(str[1 : len(str)-1])
+		if !ok {
+			return nil, ErrBadTerminal
+		}
+		return Literal(rs), nil
+	case first == '[' && last == ']':
+		return parseClass(str[1 : len(str)-1])
+	}
+	return nil, ErrBadTerminal
+}
+
+// IsTerminal returns true if the string can be parsed as a Terminal.
+func IsTerminal(str string) bool {
+	_, err := ParseTerminal(str)
+	return err == nil
+}
+
+func parseClass(str string) (Terminal, error) {
+	c := &Class{}
+	if strings.HasPrefix(str, "^") {
+		c.Negate = true
+		str = str[1:]
+	}
+	rs, ok := unescapeRunes(str)
+	if !ok || len(rs) == 0 {
+		return nil, ErrBadTerminal
+	}
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		if i+2 < len(rs) && rs[i+1].r == '-' && !rs[i+1].escaped {
+			if rs[i+2].r < r.r {
+				return nil, ErrBadTerminal
+			}
+			c.Ranges = append(c.Ranges, r.r, rs[i+2].r)
+			i += 2
+			continue
+		}
+		c.Ranges = append(c.Ranges, r.r, r.r)
+	}
+	return c, nil
+}
+
+type escRune struct {
+	r       rune
+	escaped bool
+}
+
+func unescape(str string) (string, bool) {
+	rs, ok := unescapeRunes(str)
+	if !ok {
+		return "", false
+	}
+	out := make([]rune, len(rs))
+	for i, r := range rs {
+		out[i] = r.r
+	}
+	return string(out), true
+}
+
+func unescapeRunes(str string) ([]escRune, bool) {
+	var out []escRune
+	escaped := false
+	for _, r := range str {
+		if !escaped && r == '\\' {
+			escaped = true
+			continue
+		}
+		if escaped {
+			switch r {
+			case 'n':
+				r = '\n'
+			case 'r':
+				r = '\r'
+			case 't':
+				r = '\t'
+			}
+		}
+		out = append(out, escRune{r, escaped})
+		escaped = false
+	}
+	return out, !escaped
+}
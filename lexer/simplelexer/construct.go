@@ -102,6 +102,28 @@ func (l *Lexer) addRule(r *rule) error {
 	return nil
 }
 
+// Rule describes one rule of the lexer.
+type Rule struct {
+	Kind    parlex.Symbol
+	Re      *regexp.Regexp
+	Discard bool
+}
+
+// Rules returns the rules of the lexer in priority order. This allows tools to
+// be built from a lexer definition.
+func (l *Lexer) Rules() []Rule {
+	rules := make([]Rule, len(l.order))
+	for i, kind := range l.order {
+		r := l.rules[kind]
+		rules[i] = Rule{
+			Kind:    l.set.ByIdx(kind),
+			Re:      r.re,
+			Discard: r.discard,
+		}
+	}
+	return rules
+}
+
 // String exports the lexer as a string. The output of String can be used to
 // make a copy of the lexer.
 func (l *Lexer) String() string {
@@ -131,3 +153,45 @@ func (l *Lexer) String() string {
 	}
 	return strings.Join(lines, "\n")
 }
+
+// Merge adds the rules of other lexers after the rules of l, so l's rules win a
+// tie. A rule for a kind l already has is skipped if it is the same rule. If
+// the rules differ, a parlex.ConflictError is returned and l is not changed.
+func (l *Lexer) Merge(others ...*Lexer) error {
+	var conflicts parlex.ConflictError
+	var add []string
+	added := make(map[string]*rule)
+	for _, o := range others {
+		for _, kind := range o.order {
+			r := o.rules[kind]
+			name := o.set.ByIdx(kind).String()
+			existing := added[name]
+			if existing == nil && l.set.Has(name) {
+				if idx := l.set.Str(name).Idx(); idx < len(l.rules) {
+					existing = l.rules[idx]
+				}
+			}
+			if existing == nil {
+				added[name] = &rule{
+					re:      r.re,
+					discard: r.discard,
+				}
+				add = append(add, name)
+			} else if existing.re.String() != r.re.String() || existing.discard != r.discard {
+				conflicts = append(conflicts, parlex.Conflict{
+					Symbol: name,
+					Reason: "different lexer rules",
+				})
+			}
+		}
+	}
+	if conflicts != nil {
+		return conflicts
+	}
+	for _, name := range add {
+		r := added[name]
+		r.kind = l.set.Str(name).Idx()
+		l.addRule(r)
+	}
+	return nil
+}
@@ -5,7 +5,9 @@
 // is all that's required. If the symbol is to match a regular expression, the
 // regexp should be delimited by /'s. A rule can optionally end with "-" to
 // indicate that the value should be dropped, which is often helpful to
-// eliminate whitespace.
+// eliminate whitespace. If KeepTrivia is set, discarded values are instead
+// attached to the neighbouring lexemes as trivia so the original string can be
+// reconstructed with parlex.Source.
 //
 // An example of the simple lexer can be seen in
 // parlex/examples/parlexmath
@@ -19,6 +19,7 @@ type Lexer struct {
 	priorityCounter int
 	Error           string
 	set             *setsymbol.Set
+	trivia          bool
 	insert          struct {
 		startKind string
 		startVal  string
@@ -35,6 +36,14 @@ func (l *Lexer) ByLength() { l.compare = lengthThenPriority }
 // length to decide a tie.
 func (l *Lexer) ByPriority() { l.compare = priorityThenLength }
 
+// KeepTrivia sets the lexer to keep discarded lexemes as trivia attached to the
+// lexemes around them, instead of dropping them. The original string can then
+// be reconstructed with parlex.Source.
+func (l *Lexer) KeepTrivia() *Lexer {
+	l.trivia = true
+	return l
+}
+
 func priorityThenLength(e1, p1, e2, p2 int) bool {
 	return p1 < p2 || (p1 == p2 && e1 > e2)
 }
@@ -66,6 +75,7 @@ type lexOp struct {
 	errStart int
 	cur      int
 	lines    int
+	tb       *lexeme.TriviaBuilder
 }
 
 // Lex takes a string and produces a slice of lexemes that can be consumed by a
@@ -76,12 +86,13 @@ func (l *Lexer) Lex(str string) []parlex.Lexeme {
 		b:     []byte(str),
 		lxs:   make([]parlex.Lexeme, 0),
 	}
-
+	if l.trivia {
+		op.tb = &lexeme.TriviaBuilder{}
+	}
 	if op.insert.startKind != "" {
-		op.lxs = append(op.lxs, lexeme.String(op.insert.startKind).Set(op.insert.startVal))
+		op.lxs = append(op.lxs, op.synthetic(op.insert.startKind, op.insert.startVal))
 	}
 	op.populateNext()
-
 	for {
 		lx, lxEnd := op.findNextMatch()
 		if lxEnd == op.cur {
@@ -92,9 +103,18 @@ func (l *Lexer) Lex(str string) []parlex.Lexeme {
 			op.cur++
 		} else {
 			op.checkError()
-			if !op.rules[lx.K.(*setsymbol.Symbol).Idx()].discard {
+			discard := op.rules[lx.K.(*setsymbol.Symbol).Idx()].discard
+			if !discard {
 				op.lxs = append(op.lxs, lx)
 			}
+			if op.tb != nil {
+				src := string(op.b[op.cur:lxEnd])
+				if discard {
+					op.tb.Discard(lx, src)
+				} else {
+					op.tb.Keep(lx, src)
+				}
+			}
 			op.cur = lxEnd
 		}
 		if op.cur >= len(op.b) {
@@ -103,14 +123,25 @@ func (l *Lexer) Lex(str string) []parlex.Lexeme {
 		op.updateNext()
 	}
 	op.checkError()
-
+	var end *lexeme.Lexeme
 	if op.insert.endKind != "" {
-		op.lxs = append(op.lxs, lexeme.String(op.insert.endKind).Set(op.insert.endVal))
+		end = op.synthetic(op.insert.endKind, op.insert.endVal)
+		op.lxs = append(op.lxs, end)
+	}
+	if op.tb != nil {
+		op.tb.Close(end)
 	}
-
 	return op.lxs
 }
 
+func (op *lexOp) synthetic(kind, val string) *lexeme.Lexeme {
+	lx := lexeme.String(kind).Set(val)
+	if op.tb != nil {
+		op.tb.Synthetic(lx)
+	}
+	return lx
+}
+
 func (op *lexOp) checkError() {
 	if !op.errFlag || op.cur > len(op.b) {
 		return
@@ -120,6 +151,9 @@ func (op *lexOp) checkError() {
 	errKind := op.set.Str(op.Error)
 	lxm := lexeme.New(errKind).Set(val)
 	op.lxs = append(op.lxs, &errLexeme{lxm})
+	if op.tb != nil {
+		op.tb.Keep(lxm, val)
+	}
 }
 
 func (op *lexOp) populateNext() {
@@ -164,6 +198,8 @@ func (op *lexOp) findNextMatch() (*lexeme.Lexeme, int) {
 	lx.C = strings.LastIndex(string(op.b[:op.cur]), "\n")
 	lx.C = op.cur - lx.C
 	op.lines += strings.Count(lx.V, "\n")
+	lx.EL = op.lines
+	lx.EC = lxEnd - strings.LastIndex(string(op.b[:lxEnd]), "\n")
 
 	return lx, lxEnd
 }
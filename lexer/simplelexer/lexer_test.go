@@ -126,3 +126,104 @@ func TestEmptyEdgeCases(t *testing.T) {
 		assert.Len(t, lxs, 0)
 	}
 }
+
+func TestKeepTrivia(t *testing.T) {
+	s := "  a // one\n  b  // two\n\n c  "
+	lxr, err := New(`
+    word    /\w+/
+    comment /\/\/[^\n]*/ -
+    space   /\s+/ -
+  `)
+	assert.NoError(t, err)
+	lxr.KeepTrivia().InsertEnd("EOF", "")
+	lxs := lxr.Lex(s)
+	if !assert.Len(t, lxs, 4) {
+		return
+	}
+	assert.Equal(t, s, parlex.Source(lxs...))
+
+	a := lxs[0].(parlex.Trivia)
+	assert.Equal(t, "  ", parlex.Source(a.Leading()...))
+	assert.Equal(t, " // one\n  ", parlex.Source(a.Trailing()...))
+
+	b := lxs[1].(parlex.Trivia)
+	assert.Len(t, b.Leading(), 0)
+	assert.Equal(t, "  // two\n\n ", parlex.Source(b.Trailing()...))
+
+	c := lxs[2].(parlex.Trivia)
+	assert.Equal(t, "  ", parlex.Source(c.Trailing()...))
+
+	src, ok := lxs[3].(parlex.Trivia).Source()
+	assert.True(t, ok)
+	assert.Equal(t, "", src)
+}
+
+func TestLexEnd(t *testing.T) {
+	lxr, err := New(`
+    word  /\w+/
+    space /\s+/
+  `)
+	assert.NoError(t, err)
+	lxs := lxr.Lex("this is \na test")
+
+	expected := [][4]int{
+		{0, 1, 0, 5},
+		{0, 5, 0, 6},
+		{0, 6, 0, 8},
+		{0, 8, 1, 1},
+		{1, 1, 1, 2},
+		{1, 2, 1, 3},
+		{1, 3, 1, 7},
+	}
+	if assert.Len(t, lxs, len(expected)) {
+		for i, e := range expected {
+			l, c := lxs[i].Pos()
+			el, ec, ok := parlex.End(lxs[i])
+			assert.True(t, ok)
+			assert.Equal(t, e, [4]int{l, c, el, ec}, lxs[i].Value())
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base, err := New(`
+    let
+    id    /[a-z]+/
+    space /\s+/ -
+  `)
+	assert.NoError(t, err)
+	expr, err := New(`
+    int   /\d+/
+    op    /[+\-]/
+    space /\s+/ -
+  `)
+	assert.NoError(t, err)
+
+	assert.NoError(t, base.Merge(expr))
+	expected := []string{"let", "id", "space", "int", "op"}
+	rules := base.Rules()
+	if assert.Len(t, rules, len(expected)) {
+		for i, r := range rules {
+			assert.Equal(t, expected[i], r.Kind.String())
+		}
+	}
+
+	lxms := base.Lex("let x 1 + 2")
+	kinds := []string{"let", "id", "int", "op", "int"}
+	if assert.Len(t, lxms, len(kinds)) {
+		for i, lx := range lxms {
+			assert.Equal(t, kinds[i], lx.Kind().String())
+		}
+	}
+
+	bad, err := New(`
+    id    /\w+/
+    int   /\d+/
+    hex   /0x[0-9a-f]+/
+  `)
+	assert.NoError(t, err)
+	err = base.Merge(bad)
+	assert.Equal(t, parlex.ConflictError{{Symbol: "id", Reason: "different lexer rules"}}, err)
+	assert.Len(t, base.Rules(), len(expected))
+	assert.False(t, base.set.Has("hex"))
+}
@@ -5,6 +5,7 @@ package stacklexer
 import (
 	"errors"
 	"fmt"
+	"github.com/adamcolton/parlex"
 	"github.com/adamcolton/parlex/lexeme"
 	"github.com/adamcolton/parlex/symbol/setsymbol"
 	"regexp"
@@ -16,10 +17,12 @@ import (
 // stack of lexers to provide more power than a simple lexer.
 type StackLexer struct {
 	lexers  map[string]*subLexer
+	names   []string
 	start   *subLexer
 	set     *setsymbol.Set
 	Error   string
 	compare func(e1, p1, e2, p2 int) bool
+	trivia  bool
 	insert  struct {
 		startKind string
 		startVal  string
@@ -50,6 +53,45 @@ type rule struct {
 	submatches []submatch
 }
 
+// Rule describes one rule of a sub-lexer. Push is the name of the sub-lexer
+// that a match will push and Pop is the number of sub-lexers a match will pop.
+type Rule struct {
+	Kind    parlex.Symbol
+	Re      *regexp.Regexp
+	Discard bool
+	Push    string
+	Pop     int
+}
+
+// Lexers returns the names of the sub-lexers in the order they were defined.
+// The first is the start lexer.
+func (l *StackLexer) Lexers() []string {
+	names := make([]string, len(l.names))
+	copy(names, l.names)
+	return names
+}
+
+// Rules returns the rules of a sub-lexer in priority order, including any
+// inherited rules. If there is no sub-lexer by that name, nil is returned.
+func (l *StackLexer) Rules(lexer string) []Rule {
+	sl, found := l.lexers[lexer]
+	if !found {
+		return nil
+	}
+	rules := make([]Rule, len(sl.order))
+	for i, kind := range sl.order {
+		r := sl.rules[kind]
+		rules[i] = Rule{
+			Kind:    l.set.ByIdx(kind),
+			Re:      r.re,
+			Discard: r.discard,
+			Push:    r.push,
+			Pop:     r.pop,
+		}
+	}
+	return rules
+}
+
 type submatch struct {
 	section int
 	str     string
@@ -109,6 +151,9 @@ func New(definitions ...string) (*StackLexer, error) {
 				StackLexer: l,
 				name:       cur.name,
 			}
+			if _, found := l.lexers[cur.name]; !found {
+				l.names = append(l.names, cur.name)
+			}
 			l.lexers[cur.name] = sl
 			if l.start == nil {
 				l.start = sl
@@ -276,9 +321,74 @@ func (l *StackLexer) ByPriority() *StackLexer {
 	return l
 }
 
+// KeepTrivia sets the lexer to keep discarded lexemes as trivia attached to the
+// lexemes around them, instead of dropping them. The original string can then
+// be reconstructed with parlex.Source.
+func (l *StackLexer) KeepTrivia() *StackLexer {
+	l.trivia = true
+	return l
+}
+
 func priorityThenLength(e1, p1, e2, p2 int) bool {
 	return p2 == -1 || p1 < p2 || (p1 == p2 && e1 > e2)
 }
 func lengthThenPriority(e1, p1, e2, p2 int) bool {
 	return e1 > e2 || (e1 == e2 && (p2 == -1 || p1 < p2))
 }
+
+// Merge adds the sub-lexers of other to l. A sub-lexer with a name that l does
+// not have is added as a new sub-lexer. The rules of a sub-lexer with the same
+// name are added after l's rules and are inherited by any sub-lexer that
+// inherits from it. The start lexer of l does not change. A rule for a kind the
+// sub-lexer already has is skipped if it is the same rule. If the rules differ,
+// a parlex.ConflictError is returned and l is not changed.
+func (l *StackLexer) Merge(other *StackLexer) error {
+	var conflicts parlex.ConflictError
+	for _, name := range other.names {
+		sl, found := l.lexers[name]
+		if !found {
+			continue
+		}
+		for _, kind := range other.lexers[name].order {
+			r := other.lexers[name].rules[kind]
+			kindStr := other.set.ByIdx(kind).String()
+			if !l.set.Has(kindStr) {
+				continue
+			}
+			idx := l.set.Str(kindStr).Idx()
+			if idx >= len(sl.rules) || sl.rules[idx] == nil {
+				continue
+			}
+			if e := sl.rules[idx]; e.re.String() != r.re.String() || e.discard != r.discard || e.push != r.push || e.pop != r.pop {
+				conflicts = append(conflicts, parlex.Conflict{
+					Symbol: name + "." + kindStr,
+					Reason: "different lexer rules",
+				})
+			}
+		}
+	}
+	if conflicts != nil {
+		return conflicts
+	}
+
+	for _, name := range other.names {
+		sl, found := l.lexers[name]
+		if !found {
+			sl = &subLexer{
+				StackLexer: l,
+				name:       name,
+			}
+			l.lexers[name] = sl
+			l.names = append(l.names, name)
+			if l.start == nil {
+				l.start = sl
+			}
+		}
+		for _, kind := range other.lexers[name].order {
+			r := *other.lexers[name].rules[kind]
+			r.kind = l.set.Str(other.set.ByIdx(kind).String()).Idx()
+			sl.addRule(r)
+		}
+	}
+	return nil
+}
@@ -20,6 +20,7 @@ type lexOp struct {
 	}
 	cur   int
 	lines int
+	tb    *lexeme.TriviaBuilder
 }
 
 // Lex fulfills parlex.Lexer. It uses the StackLexer to lex a string
@@ -31,20 +32,36 @@ func (l *StackLexer) Lex(str string) []parlex.Lexeme {
 		lxs:      make([]parlex.Lexeme, 0),
 	}
 	op.err.kind = l.set.Str(op.Error)
+	if l.trivia {
+		op.tb = &lexeme.TriviaBuilder{}
+	}
 	if op.insert.startKind != "" {
-		op.lxs = append(op.lxs, lexeme.String(op.insert.startKind).Set(op.insert.startVal))
+		op.lxs = append(op.lxs, op.synthetic(op.insert.startKind, op.insert.startVal))
 	}
 	op.populateNext()
 
 	op.lex()
 
+	var end *lexeme.Lexeme
 	if op.insert.endKind != "" {
-		op.lxs = append(op.lxs, lexeme.String(op.insert.endKind).Set(op.insert.endVal))
+		end = op.synthetic(op.insert.endKind, op.insert.endVal)
+		op.lxs = append(op.lxs, end)
+	}
+	if op.tb != nil {
+		op.tb.Close(end)
 	}
 
 	return op.lxs
 }
 
+func (op *lexOp) synthetic(kind, val string) *lexeme.Lexeme {
+	lx := lexeme.String(kind).Set(val)
+	if op.tb != nil {
+		op.tb.Synthetic(lx)
+	}
+	return lx
+}
+
 func (op *lexOp) lex() {
 	for {
 		lx, lxEnd, r := op.findNextMatch()
@@ -64,6 +81,14 @@ func (op *lexOp) lex() {
 		if !r.discard {
 			op.lxs = append(op.lxs, lx)
 		}
+		if op.tb != nil {
+			src := string(op.b[op.cur:lxEnd])
+			if r.discard {
+				op.tb.Discard(lx, src)
+			} else {
+				op.tb.Keep(lx, src)
+			}
+		}
 		op.cur = lxEnd
 		if op.cur >= len(op.b) {
 			break
@@ -146,9 +171,14 @@ func (op *lexOp) findNextMatch() (*lexeme.Lexeme, int, *rule) {
 
 func (op *lexOp) handleLineCol(lx *lexeme.Lexeme, str string) {
 	lx.L = op.lines
-	lx.C = strings.LastIndex(string(op.b[:op.cur]), "\n")
-	lx.C = op.cur - lx.C
+	lx.C = op.col(op.cur)
 	op.lines += strings.Count(str, "\n")
+	lx.EL, lx.EC = op.lines, op.col(op.cur+len(str))
+}
+
+// col returns the column of the offset into the input.
+func (op *lexOp) col(offset int) int {
+	return offset - strings.LastIndex(string(op.b[:offset]), "\n")
 }
 
 func (op *lexOp) checkError() {
@@ -158,9 +188,13 @@ func (op *lexOp) checkError() {
 	op.err.flag = false
 	val := string(op.b[op.err.start:op.cur])
 	lx := lexeme.New(op.err.kind).Set(val)
-	op.handleLineCol(lx, lx.V)
-	lx.C -= len(val)
+	lx.L, lx.C = op.lines, op.col(op.cur)-len(val)
+	op.lines += strings.Count(val, "\n")
+	lx.EL, lx.EC = op.lines, op.col(op.cur)
 	op.lxs = append(op.lxs, &errLexeme{lx})
+	if op.tb != nil {
+		op.tb.Keep(lx, val)
+	}
 }
 
 func (op *lexOp) updateNext() {
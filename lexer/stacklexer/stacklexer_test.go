@@ -176,16 +176,20 @@ func TestLexerError(t *testing.T) {
 	errs := parlex.LexErrors(lxms)
 	expected := []parlex.LexError{
 		&errLexeme{&lexeme.Lexeme{
-			K: lxr.set.Str(lxr.Error),
-			V: "error1",
-			L: 1,
-			C: 9,
+			K:  lxr.set.Str(lxr.Error),
+			V:  "error1",
+			L:  1,
+			C:  9,
+			EL: 1,
+			EC: 15,
 		}},
 		&errLexeme{&lexeme.Lexeme{
-			K: lxr.set.Str(lxr.Error),
-			V: "error2",
-			L: 1,
-			C: 20,
+			K:  lxr.set.Str(lxr.Error),
+			V:  "error2",
+			L:  1,
+			C:  20,
+			EL: 1,
+			EC: 26,
 		}},
 	}
 	assert.Equal(t, expected, errs)
@@ -240,3 +244,64 @@ func TestEmptyEdgeCases(t *testing.T) {
 		assert.Len(t, lxs, 0)
 	}
 }
+
+func TestStacklexerKeepTrivia(t *testing.T) {
+	lxr := Must(`
+    == main ==
+      quote /"/ str -
+      word  /\w+/
+      space /\s+/ -
+    == str ==
+      close /"/ ^ -
+      chars /([^"]*)/ (1)
+  `).KeepTrivia()
+	s := ` say "hello there" now `
+	lxs := lxr.Lex(s)
+	if !assert.Len(t, lxs, 3) {
+		return
+	}
+	assert.Equal(t, "hello there", lxs[1].Value())
+	assert.Equal(t, s, parlex.Source(lxs...))
+}
+
+func TestMerge(t *testing.T) {
+	lxr, err := New(`
+    == main ==
+      START inner
+      word  /[a-z]+/
+      shared
+    == inner ==
+      STOP ^
+      shared
+    == shared ==
+      space /\s+/ -
+  `)
+	assert.NoError(t, err)
+	literals, err := New(`
+    == shared ==
+      int /\d+/
+    == str ==
+      quote ^
+      chars /[^"]+/
+  `)
+	assert.NoError(t, err)
+	assert.NoError(t, lxr.Merge(literals))
+	assert.Equal(t, []string{"main", "inner", "shared", "str"}, lxr.Lexers())
+
+	// int is inherited from shared by main and inner
+	lxms := lxr.Lex("a 1 START 2 STOP")
+	kinds := []string{"word", "int", "START", "int", "STOP"}
+	if assert.Len(t, lxms, len(kinds)) {
+		for i, lx := range lxms {
+			assert.Equal(t, kinds[i], lx.Kind().String())
+		}
+	}
+
+	bad, err := New(`
+    == inner ==
+      STOP ^^
+  `)
+	assert.NoError(t, err)
+	err = lxr.Merge(bad)
+	assert.Equal(t, parlex.ConflictError{{Symbol: "inner.STOP", Reason: "different lexer rules"}}, err)
+}
@@ -49,3 +49,12 @@ func New(lexer Lexer, parser Parser, reducers ...Reducer) *Runner {
 func (r *Runner) Run(input string) (ParseNode, error) {
 	return Run(input, r.lexer, r.parser, r.reducers...)
 }
+
+// Lexer returns the Lexer used by the Runner.
+func (r *Runner) Lexer() Lexer { return r.lexer }
+
+// Parser returns the Parser used by the Runner.
+func (r *Runner) Parser() Parser { return r.parser }
+
+// Reducers returns the Reducers used by the Runner.
+func (r *Runner) Reducers() []Reducer { return r.reducers }
@@ -0,0 +1,94 @@
+package parlextest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/parser/action"
+)
+
+// Rule is a single production in a grammar and the number of times it was used.
+type Rule struct {
+	NonTerminal string
+	Production  int
+	Symbols     []string
+	Count       int
+}
+
+func (r *Rule) String() string {
+	return strings.TrimSpace(r.NonTerminal + " -> " + strings.Join(r.Symbols, " "))
+}
+
+// Coverage tracks how many times each production in a grammar is used.
+type Coverage struct {
+	Rules []*Rule
+}
+
+// NewCoverage creates a Coverage with every production in the grammar.
+func NewCoverage(grmr parlex.Grammar) *Coverage {
+	c := &Coverage{}
+	for _, nt := range grmr.NonTerminals() {
+		ntStr := nt.String()
+		for i := grmr.Productions(nt).Iter(); i.Next(); {
+			r := &Rule{
+				NonTerminal: ntStr,
+				Production:  i.Idx,
+				Symbols:     make([]string, i.Symbols()),
+			}
+			for j := range r.Symbols {
+				r.Symbols[j] = i.Symbol(j).String()
+			}
+			c.Rules = append(c.Rules, r)
+		}
+	}
+	return c
+}
+
+// Actions returns actions that count each production as it is used. They can
+// be passed to the ParseActions method of a parser such as packrat or topdown.
+func (c *Coverage) Actions() *action.Actions {
+	a := action.New()
+	for _, r := range c.Rules {
+		r := r
+		a.Add(r.NonTerminal, r.Production, func(node parlex.ParseNode, args []interface{}) interface{} {
+			r.Count++
+			return nil
+		})
+	}
+	return a
+}
+
+// Uncovered returns the rules that were never used.
+func (c *Coverage) Uncovered() []*Rule {
+	var out []*Rule
+	for _, r := range c.Rules {
+		if r.Count == 0 {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Ratio returns the fraction of rules that were used.
+func (c *Coverage) Ratio() float64 {
+	if len(c.Rules) == 0 {
+		return 1
+	}
+	return float64(len(c.Rules)-len(c.Uncovered())) / float64(len(c.Rules))
+}
+
+// String returns a report with the count for each rule. Rules that were never
+// used are marked with a !.
+func (c *Coverage) String() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "coverage: %.1f%% of %d rules\n", c.Ratio()*100, len(c.Rules))
+	for _, r := range c.Rules {
+		mark := " "
+		if r.Count == 0 {
+			mark = "!"
+		}
+		fmt.Fprintf(&buf, "%s %4d %s\n", mark, r.Count, r.String())
+	}
+	return buf.String()
+}
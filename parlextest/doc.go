@@ -0,0 +1,28 @@
+// Package parlextest runs a corpus of inputs against a parlex.Runner.
+//
+// A corpus is a directory of input files ending in .in. Each input is paired
+// with a golden file of the same name:
+//
+//	name.tree  the expected tree in the tree.New format
+//	name.fail  the input is expected to fail; if the file is not empty, the
+//	           error message must contain it's contents
+//
+// Trees are compared ignoring positions and a failure is reported as a unified
+// diff. Running the tests with -parlextest.update rewrites the .tree files from the
+// current output and creates goldens for inputs that do not have one; .fail
+// files are never rewritten.
+//
+// If the Corpus has a Grammar, Run also returns the per-production Coverage of
+// the corpus. Coverage is counted from the derivation recorded by parsers with a
+// ParseActions method, like packrat and topdown; other parsers are run without
+// coverage.
+//
+//	func TestCorpus(t *testing.T) {
+//	  cov := (&parlextest.Corpus{
+//	    Dir:     "testdata",
+//	    Runner:  runner,
+//	    Grammar: grmr,
+//	  }).Run(t)
+//	  assert.Empty(t, cov.Uncovered())
+//	}
+package parlextest
@@ -0,0 +1,168 @@
+package parlextest
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/parser/action"
+	"github.com/adamcolton/parlex/tree"
+	"github.com/adamcolton/parlex/tree/diff"
+)
+
+// Update is set by the -parlextest.update flag. When true, Run rewrites the
+// golden files.
+var Update = flag.Bool("parlextest.update", false, "update parlextest golden files")
+
+// File extensions used in a corpus
+const (
+	InputExt = ".in"
+	TreeExt  = ".tree"
+	FailExt  = ".fail"
+)
+
+// Corpus runs a directory of inputs against a Runner. Grammar is optional and
+// is only used for coverage, which also requires the Parser of the Runner to
+// support actions.
+type Corpus struct {
+	Dir     string
+	Runner  *parlex.Runner
+	Grammar parlex.Grammar
+}
+
+// Cases returns the names of the inputs in the corpus, without the extension.
+func (c *Corpus) Cases() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(c.Dir, "*"+InputExt))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = strings.TrimSuffix(filepath.Base(f), InputExt)
+	}
+	return names, nil
+}
+
+// Run runs each case in the corpus as a subtest. If the Corpus has a Grammar,
+// the coverage of the corpus is returned.
+func (c *Corpus) Run(t *testing.T) *Coverage {
+	names, err := c.Cases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) == 0 {
+		t.Fatalf("no %s files in %s", InputExt, c.Dir)
+	}
+	var cov *Coverage
+	if c.Grammar != nil {
+		cov = NewCoverage(c.Grammar)
+	}
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			c.runCase(t, name, cov)
+		})
+	}
+	return cov
+}
+
+func (c *Corpus) path(name, ext string) string {
+	return filepath.Join(c.Dir, name+ext)
+}
+
+func (c *Corpus) runCase(t *testing.T, name string, cov *Coverage) {
+	input, err := ioutil.ReadFile(c.path(name, InputExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, runErr := c.run(string(input), cov)
+
+	if fail, err := ioutil.ReadFile(c.path(name, FailExt)); err == nil {
+		if runErr == nil {
+			t.Errorf("expected failure, got:\n%s", str(got))
+			return
+		}
+		if msg := strings.TrimSpace(string(fail)); msg != "" && !strings.Contains(runErr.Error(), msg) {
+			t.Errorf("expected error containing %q, got %q", msg, runErr.Error())
+		}
+		return
+	}
+
+	treePath := c.path(name, TreeExt)
+	golden, err := ioutil.ReadFile(treePath)
+	if os.IsNotExist(err) {
+		if !*Update {
+			t.Fatalf("no %s or %s file, run with -parlextest.update to create one", TreeExt, FailExt)
+		}
+		if runErr != nil {
+			c.write(t, c.path(name, FailExt), runErr.Error()+"\n")
+			return
+		}
+		c.write(t, treePath, str(got))
+		return
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	if runErr != nil {
+		t.Fatalf("unexpected error: %s", runErr)
+	}
+	expected, err := tree.New(string(golden))
+	if err != nil {
+		t.Fatalf("bad golden file %s: %s", treePath, err)
+	}
+	if u := diff.Unified(expected, got, tree.IgnorePos); u != "" {
+		if *Update {
+			c.write(t, treePath, str(got))
+			return
+		}
+		t.Error(u)
+	}
+}
+
+// actionParser is fulfilled by parsers that record the production used for each
+// node, like packrat and topdown.
+type actionParser interface {
+	ParseActions(lexemes []parlex.Lexeme, actions *action.Actions) (interface{}, parlex.ParseNode)
+}
+
+// parseFunc fulfills parlex.Parser.
+type parseFunc func(lexemes []parlex.Lexeme) parlex.ParseNode
+
+func (fn parseFunc) Parse(lexemes []parlex.Lexeme) parlex.ParseNode { return fn(lexemes) }
+
+// run the input through the Runner, counting the productions of the derivation
+// if there is a Coverage.
+func (c *Corpus) run(input string, cov *Coverage) (parlex.ParseNode, error) {
+	ap, ok := c.Runner.Parser().(actionParser)
+	if cov == nil || !ok {
+		return c.Runner.Run(input)
+	}
+	actions := cov.Actions()
+	parser := parseFunc(func(lexemes []parlex.Lexeme) parlex.ParseNode {
+		_, node := ap.ParseActions(lexemes, actions)
+		return node
+	})
+	return parlex.Run(input, c.Runner.Lexer(), parser, c.Runner.Reducers()...)
+}
+
+func (c *Corpus) write(t *testing.T, path, contents string) {
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func str(node parlex.ParseNode) string {
+	if node == nil {
+		return ""
+	}
+	pn, ok := node.(*tree.PN)
+	if !ok {
+		pn = tree.Clone(node)
+	}
+	return pn.String()
+}
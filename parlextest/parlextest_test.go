@@ -0,0 +1,54 @@
+package parlextest
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/grammar"
+	"github.com/adamcolton/parlex/lexer/simplelexer"
+	"github.com/adamcolton/parlex/parser/packrat"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	lxr = parlex.MustLexer(simplelexer.New(`
+    ( /\(/
+    ) /\)/
+    op /[+\-\*\/]/
+    int /\d+/
+    space /\s+/ -
+  `))
+	grmr = parlex.MustGrammar(grammar.New(`
+    E -> T op E
+      -> T
+    T -> ( E )
+      -> int
+  `))
+	runner = parlex.New(lxr, packrat.New(grmr))
+)
+
+func TestCorpus(t *testing.T) {
+	c := &Corpus{
+		Dir:     "testdata",
+		Runner:  runner,
+		Grammar: grmr,
+	}
+	names, err := c.Cases()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"add", "bad", "int"}, names)
+
+	cov := c.Run(t)
+	if assert.Len(t, cov.Uncovered(), 1) {
+		assert.Equal(t, "T -> ( E )", cov.Uncovered()[0].String())
+	}
+	assert.Equal(t, 0.75, cov.Ratio())
+	assert.True(t, strings.HasPrefix(cov.String(), "coverage: 75.0% of 4 rules\n"))
+	assert.Contains(t, cov.String(), "!    0 T -> ( E )\n")
+}
+
+func TestUpdateFlag(t *testing.T) {
+	assert.NotNil(t, flag.Lookup("parlextest.update"))
+	assert.Nil(t, flag.Lookup("update"))
+}
@@ -0,0 +1,149 @@
+package action
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/symbol/stringsymbol"
+	"github.com/adamcolton/parlex/tree"
+)
+
+// ErrNoProduction is returned by AddRule if the rule is not a production in
+// the grammar.
+var ErrNoProduction = errors.New("Production not found in grammar")
+
+// Action is invoked when a production completes. The node is the parse node
+// built for the production and args holds the value of each of it's children.
+// The value returned becomes the argument passed to the parent's action.
+type Action func(node parlex.ParseNode, args []interface{}) interface{}
+
+// Actions holds the semantic actions for the productions of a grammar. A
+// production without an action passes up the value of it's first child, or
+// nil if it has no children. Terminals pass up the result of Terminal, or the
+// lexeme if Terminal is nil.
+type Actions struct {
+	prods    map[string][]Action
+	Terminal func(lx parlex.Lexeme) interface{}
+}
+
+// New returns an empty set of Actions.
+func New() *Actions {
+	return &Actions{
+		prods: make(map[string][]Action),
+	}
+}
+
+// Add an action for the production of the non-terminal at index prod.
+func (a *Actions) Add(nonterminal string, prod int, fn Action) *Actions {
+	fns := a.prods[nonterminal]
+	if ln := prod + 1; len(fns) < ln {
+		fns = append(fns, make([]Action, ln-len(fns))...)
+	}
+	fns[prod] = fn
+	a.prods[nonterminal] = fns
+	return a
+}
+
+// AddRule finds the production described by rule, in the form "E -> E op E",
+// in the grammar and adds an action for it. An empty production is written
+// "E ->".
+func (a *Actions) AddRule(grmr parlex.Grammar, rule string, fn Action) error {
+	nonterminal, prod, ok := Find(grmr, rule)
+	if !ok {
+		return ErrNoProduction
+	}
+	a.Add(nonterminal, prod, fn)
+	return nil
+}
+
+// MustAddRule calls AddRule and panics if there is an error.
+func (a *Actions) MustAddRule(grmr parlex.Grammar, rule string, fn Action) *Actions {
+	if err := a.AddRule(grmr, rule, fn); err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Find returns the non-terminal and production index for a rule in the form
+// "E -> E op E".
+func Find(grmr parlex.Grammar, rule string) (string, int, bool) {
+	sides := strings.SplitN(rule, "->", 2)
+	if len(sides) != 2 {
+		return "", -1, false
+	}
+	nonterminal := strings.TrimSpace(sides[0])
+	symbols := strings.Fields(sides[1])
+	prods := grmr.Productions(stringsymbol.Symbol(nonterminal))
+	if prods == nil {
+		return "", -1, false
+	}
+	for i := prods.Iter(); i.Next(); {
+		if i.Symbols() != len(symbols) {
+			continue
+		}
+		match := true
+		for j, s := range symbols {
+			if i.Symbol(j).String() != s {
+				match = false
+				break
+			}
+		}
+		if match {
+			return nonterminal, i.Idx, true
+		}
+	}
+	return "", -1, false
+}
+
+// Action returns the action for a production, which may be nil.
+func (a *Actions) Action(nonterminal string, prod int) Action {
+	fns := a.prods[nonterminal]
+	if prod < 0 || prod >= len(fns) {
+		return nil
+	}
+	return fns[prod]
+}
+
+// Eval runs the actions bottom up over a derivation. The production func
+// returns the index of the production a node was derived from, or -1 if the
+// node is a terminal. Parsers that support actions call Eval on the accepted
+// derivation so that actions are never run on alternatives that were
+// discarded.
+func (a *Actions) Eval(node parlex.ParseNode, production func(parlex.ParseNode) int) interface{} {
+	prod := production(node)
+	if prod < 0 {
+		if a.Terminal != nil {
+			return a.Terminal(node)
+		}
+		return parlex.Lexeme(node)
+	}
+	args := make([]interface{}, node.Children())
+	for i := range args {
+		args[i] = a.Eval(node.Child(i), production)
+	}
+	if fn := a.Action(node.Kind().String(), prod); fn != nil {
+		return fn(node, args)
+	}
+	if len(args) > 0 {
+		return args[0]
+	}
+	return nil
+}
+
+// Derivation records the production each non-terminal node in a parse tree was
+// derived from. It is populated by parsers that support actions.
+type Derivation map[*tree.PN]int
+
+// Production returns the index of the production the node was derived from or
+// -1 if the node is not in the Derivation. It can be passed to Actions.Eval.
+func (d Derivation) Production(node parlex.ParseNode) int {
+	pn, ok := node.(*tree.PN)
+	if !ok {
+		return -1
+	}
+	if prod, ok := d[pn]; ok {
+		return prod
+	}
+	return -1
+}
@@ -0,0 +1,84 @@
+package action
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/grammar"
+	"github.com/adamcolton/parlex/tree"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFind(t *testing.T) {
+	grmr, err := grammar.New(`
+    E -> E op T
+      -> T
+    T -> ( E )
+      -> int
+      ->
+  `)
+	assert.NoError(t, err)
+
+	tt := map[string]int{
+		"E -> E op T": 0,
+		"E -> T":      1,
+		"T -> ( E )":  0,
+		"T->int":      1,
+		"T ->":        2,
+		"E -> int":    -1,
+		"X -> T":      -1,
+		"E T":         -1,
+	}
+	for rule, expected := range tt {
+		_, idx, ok := Find(grmr, rule)
+		assert.Equal(t, expected, idx, rule)
+		assert.Equal(t, expected > -1, ok, rule)
+	}
+
+	a := New()
+	assert.Equal(t, ErrNoProduction, a.AddRule(grmr, "E -> int", nil))
+	assert.Panics(t, func() { a.MustAddRule(grmr, "E -> int", nil) })
+}
+
+func TestEval(t *testing.T) {
+	root, err := tree.New(`
+    E {
+      E {
+        T {
+          int: "1"
+        }
+      }
+      op: "+"
+      T {
+        int: "2"
+      }
+    }
+  `)
+	assert.NoError(t, err)
+	d := Derivation{
+		root:           0,
+		root.C[0]:      1,
+		root.C[0].C[0]: 1,
+		root.C[2]:      1,
+	}
+
+	a := New().
+		Add("E", 0, func(node parlex.ParseNode, args []interface{}) interface{} {
+			return args[0].(int) + args[2].(int)
+		})
+	a.Terminal = func(lx parlex.Lexeme) interface{} {
+		i, _ := strconv.Atoi(lx.Value())
+		return i
+	}
+	assert.Equal(t, 3, a.Eval(root, d.Production))
+
+	a.Terminal = nil
+	a.Add("T", 1, func(node parlex.ParseNode, args []interface{}) interface{} {
+		i, _ := strconv.Atoi(args[0].(parlex.Lexeme).Value())
+		return i * 10
+	})
+	assert.Equal(t, 30, a.Eval(root, d.Production))
+	assert.Nil(t, a.Action("E", 1))
+	assert.Nil(t, a.Action("X", 0))
+}
@@ -0,0 +1,19 @@
+// Package action attaches semantic actions to the productions of a grammar.
+// Unlike a tree.Reducer, which is keyed by non-terminal, an action is keyed by
+// the production so the actions for "E -> E op E" and "E -> ( E )" can differ.
+// Each action receives the values produced for it's children and returns the
+// value for the node, so a parse can produce user values directly.
+//
+// Parsers that support actions, such as packrat and topdown, provide a
+// ParseActions method that records the production used for each node of the
+// accepted derivation and then runs the actions bottom up.
+//
+//	actions := action.New().
+//	  MustAddRule(grmr, "E -> E op E", func(node parlex.ParseNode, args []interface{}) interface{} {
+//	    return apply(args[1], args[0], args[2])
+//	  }).
+//	  MustAddRule(grmr, "E -> ( E )", func(node parlex.ParseNode, args []interface{}) interface{} {
+//	    return args[1]
+//	  })
+//	val, tree := packrat.New(grmr).ParseActions(lexemes, actions)
+package action
@@ -0,0 +1,301 @@
+package cyk
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/tree"
+)
+
+type prodKey struct {
+	nonterminal string
+	production  int
+}
+
+// compiled holds the grammar after it is converted for the chart. Every
+// production is turned into rules with at most two symbols: long productions
+// are split with added symbols, terminals in long productions are replaced by
+// added symbols and nullable symbols are removed by adding a rule without
+// them. Unlike strict CNF, unary rules are kept and applied in each cell so the
+// original tree can be rebuilt.
+type compiled struct {
+	start     int
+	startName string
+	syms      map[string]int
+	nts       map[string]bool
+	terms     map[string]int
+	lexical   map[string][]*rule
+	unaries   []*rule
+	binary    []*rule
+	empty     map[string]emptyDeriv
+}
+
+// emptyDeriv is the most probable derivation of the empty string for a
+// nullable non-terminal.
+type emptyDeriv struct {
+	score float64
+	syms  []string
+}
+
+// part of the output of a rule. Either a child of the rule or the most
+// probable empty tree of a nullable non-terminal that was removed.
+type part struct {
+	hole  int
+	fixed string
+}
+
+type rule struct {
+	lhs   int
+	rhs   []int
+	score float64
+	// name is the kind of the node the rule produces. It is empty for symbols
+	// added by the conversion, their nodes are spliced into the parent.
+	name  string
+	parts []part
+}
+
+func (r *rule) apply(children [][]*tree.PN, c *compiled) []*tree.PN {
+	var nodes []*tree.PN
+	for _, p := range r.parts {
+		if p.hole < 0 {
+			nodes = append(nodes, c.emptyTree(p.fixed))
+		} else {
+			nodes = append(nodes, children[p.hole]...)
+		}
+	}
+	if r.name == "" {
+		return nodes
+	}
+	return []*tree.PN{newNode(r.name, nodes)}
+}
+
+func (c *compiled) emptyTree(nt string) *tree.PN {
+	d := c.empty[nt]
+	children := make([]*tree.PN, len(d.syms))
+	for i, s := range d.syms {
+		children[i] = c.emptyTree(s)
+	}
+	return newNode(nt, children)
+}
+
+type weighted struct {
+	nt    string
+	syms  []string
+	score float64
+}
+
+func compile(grmr parlex.Grammar, weights map[prodKey]float64) *compiled {
+	nts := grmr.NonTerminals()
+	if len(nts) == 0 {
+		return nil
+	}
+	c := &compiled{
+		syms:    make(map[string]int),
+		nts:     make(map[string]bool),
+		terms:   make(map[string]int),
+		lexical: make(map[string][]*rule),
+		empty:   make(map[string]emptyDeriv),
+	}
+	for _, nt := range nts {
+		c.nts[nt.String()] = true
+	}
+	c.startName = nts[0].String()
+	c.start = c.id(c.startName)
+
+	var prods []weighted
+	for _, nt := range nts {
+		name := nt.String()
+		var ws []float64
+		var total float64
+		for i := grmr.Productions(nt).Iter(); i.Next(); {
+			w, ok := weights[prodKey{name, i.Idx}]
+			if !ok {
+				w = 1
+			}
+			ws = append(ws, w)
+			total += w
+		}
+		for i := grmr.Productions(nt).Iter(); i.Next(); {
+			if ws[i.Idx] <= 0 {
+				continue
+			}
+			p := weighted{
+				nt:    name,
+				score: math.Log(ws[i.Idx] / total),
+			}
+			for j := i.Iter(); j.Next(); {
+				p.syms = append(p.syms, j.Symbol.String())
+			}
+			prods = append(prods, p)
+		}
+	}
+
+	c.findEmpty(prods)
+	for _, p := range prods {
+		c.addProduction(p)
+	}
+	return c
+}
+
+func (c *compiled) id(name string) int {
+	id, ok := c.syms[name]
+	if !ok {
+		id = len(c.syms)
+		c.syms[name] = id
+	}
+	return id
+}
+
+// findEmpty finds the most probable empty derivation of each nullable
+// non-terminal. All scores are at most 0 so the best derivations never repeat
+// a non-terminal and there are at most as many passes as productions.
+func (c *compiled) findEmpty(prods []weighted) {
+	for pass := 0; pass <= len(prods); pass++ {
+		changed := false
+		for _, p := range prods {
+			score := p.score
+			ok := true
+			for _, s := range p.syms {
+				d, nullable := c.empty[s]
+				if !nullable {
+					ok = false
+					break
+				}
+				score += d.score
+			}
+			if !ok {
+				continue
+			}
+			if d, found := c.empty[p.nt]; !found || score > d.score {
+				c.empty[p.nt] = emptyDeriv{score, p.syms}
+				changed = true
+			}
+		}
+		if !changed {
+			return
+		}
+	}
+}
+
+// item is a symbol of a production that is either kept or replaced by it's
+// empty tree.
+type item struct {
+	sym  string
+	kept bool
+}
+
+// addProduction adds a rule for every combination of removing the nullable
+// symbols in the production.
+func (c *compiled) addProduction(p weighted) {
+	var nullable []int
+	for i, s := range p.syms {
+		if _, ok := c.empty[s]; ok {
+			nullable = append(nullable, i)
+		}
+	}
+	for mask := 0; mask < 1<<uint(len(nullable)); mask++ {
+		items := make([]item, len(p.syms))
+		for i, s := range p.syms {
+			items[i] = item{sym: s, kept: true}
+		}
+		score := p.score
+		for bit, idx := range nullable {
+			if mask&(1<<uint(bit)) != 0 {
+				items[idx].kept = false
+				score += c.empty[p.syms[idx]].score
+			}
+		}
+		if countKept(items) > 0 {
+			c.addRule(c.id(p.nt), p.nt, items, score)
+		}
+	}
+}
+
+func countKept(items []item) int {
+	ct := 0
+	for _, it := range items {
+		if it.kept {
+			ct++
+		}
+	}
+	return ct
+}
+
+func partsFor(items []item, hole int) []part {
+	ps := make([]part, len(items))
+	for i, it := range items {
+		if it.kept {
+			ps[i] = part{hole: hole}
+		} else {
+			ps[i] = part{hole: -1, fixed: it.sym}
+		}
+	}
+	return ps
+}
+
+// addRule adds the rules for a sequence of items with at least one kept item.
+func (c *compiled) addRule(lhs int, name string, items []item, score float64) {
+	first := 0
+	for !items[first].kept {
+		first++
+	}
+	head, rest := items[:first+1], items[first+1:]
+
+	if countKept(rest) == 0 {
+		r := &rule{
+			lhs:   lhs,
+			score: score,
+			name:  name,
+			parts: partsFor(items, 0),
+		}
+		s := items[first].sym
+		if c.nts[s] {
+			r.rhs = []int{c.id(s)}
+			c.unaries = append(c.unaries, r)
+		} else {
+			c.lexical[s] = append(c.lexical[s], r)
+		}
+		return
+	}
+
+	r := &rule{
+		lhs:   lhs,
+		score: score,
+		name:  name,
+		parts: partsFor(head, 0),
+	}
+	r.rhs = []int{c.symID(items[first].sym)}
+	if countKept(rest) == 1 {
+		for _, it := range rest {
+			if it.kept {
+				r.rhs = append(r.rhs, c.symID(it.sym))
+			}
+		}
+		r.parts = append(r.parts, partsFor(rest, 1)...)
+	} else {
+		x := c.id("\x00x" + strconv.Itoa(len(c.syms)))
+		r.rhs = append(r.rhs, x)
+		r.parts = append(r.parts, part{hole: 1})
+		c.addRule(x, "", rest, 0)
+	}
+	c.binary = append(c.binary, r)
+}
+
+// symID returns the id for a symbol in a binary rule. A terminal is replaced
+// by an added symbol with a lexical rule.
+func (c *compiled) symID(s string) int {
+	if c.nts[s] {
+		return c.id(s)
+	}
+	if id, ok := c.terms[s]; ok {
+		return id
+	}
+	id := c.id("\x00t" + s)
+	c.terms[s] = id
+	c.lexical[s] = append(c.lexical[s], &rule{
+		lhs:   id,
+		parts: []part{{hole: 0}},
+	})
+	return id
+}
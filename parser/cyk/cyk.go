@@ -0,0 +1,286 @@
+package cyk
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/lexeme"
+	"github.com/adamcolton/parlex/parser/action"
+	"github.com/adamcolton/parlex/symbol/stringsymbol"
+	"github.com/adamcolton/parlex/tree"
+)
+
+// CYK is a chart parser for any context free grammar. Productions can be
+// weighted; the weights of the productions of each non-terminal are normalized
+// into probabilities so the grammar is a PCFG.
+type CYK struct {
+	parlex.Grammar
+	weights map[prodKey]float64
+	mux     sync.Mutex
+	c       *compiled
+}
+
+// Parse is a parse tree with it's score. The score is the log of the
+// probability of the derivation.
+type Parse struct {
+	Tree  *tree.PN
+	Score float64
+}
+
+// Prob returns the probability of the parse.
+func (p Parse) Prob() float64 {
+	return math.Exp(p.Score)
+}
+
+// New returns a CYK parser. All productions start with a weight of 1.
+func New(grmr parlex.Grammar) *CYK {
+	return &CYK{
+		Grammar: grmr,
+		weights: make(map[prodKey]float64),
+	}
+}
+
+// Constructor fulfills parlex.ParserConstructor
+func Constructor(grmr parlex.Grammar) (parlex.Parser, error) {
+	return New(grmr), nil
+}
+
+// Weight sets the weight of a production. The production index is the same as
+// the index in the grammar.
+func (c *CYK) Weight(nonterminal string, production int, weight float64) *CYK {
+	c.mux.Lock()
+	c.weights[prodKey{nonterminal, production}] = weight
+	c.c = nil
+	c.mux.Unlock()
+	return c
+}
+
+// WeightRule sets the weight of a production given as a rule string like
+// "E -> E op E". If the rule is not in the grammar, action.ErrNoProduction is
+// returned.
+func (c *CYK) WeightRule(rule string, weight float64) error {
+	nt, idx, ok := action.Find(c.Grammar, rule)
+	if !ok {
+		return action.ErrNoProduction
+	}
+	c.Weight(nt, idx, weight)
+	return nil
+}
+
+func (c *CYK) compiled() *compiled {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.c == nil {
+		c.c = compile(c.Grammar, c.weights)
+	}
+	return c.c
+}
+
+// Parse fulfills parlex.Parser. It returns the most probable parse tree or nil
+// if the lexemes cannot be parsed.
+func (c *CYK) Parse(lexemes []parlex.Lexeme) parlex.ParseNode {
+	ps := c.KBest(lexemes, 1)
+	if len(ps) == 0 {
+		return nil
+	}
+	return ps[0].Tree
+}
+
+// Best returns the most probable parse. If the lexemes cannot be parsed, false
+// is returned.
+func (c *CYK) Best(lexemes []parlex.Lexeme) (Parse, bool) {
+	ps := c.KBest(lexemes, 1)
+	if len(ps) == 0 {
+		return Parse{}, false
+	}
+	return ps[0], true
+}
+
+// KBest returns up to k parses ordered from most to least probable. An empty
+// input only has the most probable empty derivation.
+func (c *CYK) KBest(lexemes []parlex.Lexeme, k int) []Parse {
+	cp := c.compiled()
+	if cp == nil || k < 1 {
+		return nil
+	}
+	if len(lexemes) == 0 {
+		e, ok := cp.empty[cp.startName]
+		if !ok {
+			return nil
+		}
+		return []Parse{{
+			Tree:  cp.emptyTree(cp.startName),
+			Score: e.score,
+		}}
+	}
+
+	op := &parseOp{
+		compiled: cp,
+		lxms:     lexemes,
+		k:        k,
+		chart:    make([][]cell, len(lexemes)),
+	}
+	op.fill()
+	es := op.chart[0][len(lexemes)-1][cp.start]
+	out := make([]Parse, len(es))
+	for i, e := range es {
+		out[i] = Parse{
+			Tree:  op.build(e)[0],
+			Score: e.score,
+		}
+	}
+	return out
+}
+
+// cell holds the k best entries for each symbol over a span.
+type cell map[int][]*entry
+
+// entry is a derivation of a symbol over a span.
+type entry struct {
+	sym         int
+	score       float64
+	rule        *rule
+	left, right *entry
+	pos         int
+	// chain holds the symbols derived by unary rules in this cell to prevent
+	// cycles
+	chain []int
+}
+
+type parseOp struct {
+	*compiled
+	lxms  []parlex.Lexeme
+	k     int
+	chart [][]cell // chart[i][j] covers lexemes i through i+j
+}
+
+func (op *parseOp) insert(c cell, e *entry) bool {
+	es := c[e.sym]
+	if len(es) == op.k && es[len(es)-1].score >= e.score {
+		return false
+	}
+	idx := sort.Search(len(es), func(i int) bool { return es[i].score < e.score })
+	es = append(es, nil)
+	copy(es[idx+1:], es[idx:])
+	es[idx] = e
+	if len(es) > op.k {
+		es = es[:op.k]
+	}
+	c[e.sym] = es
+	return true
+}
+
+func (op *parseOp) fill() {
+	n := len(op.lxms)
+	for i := range op.chart {
+		op.chart[i] = make([]cell, n-i)
+	}
+	for i, lx := range op.lxms {
+		c := make(cell)
+		for _, r := range op.lexical[lx.Kind().String()] {
+			op.insert(c, &entry{
+				sym:   r.lhs,
+				score: r.score,
+				rule:  r,
+				pos:   i,
+			})
+		}
+		op.unary(c)
+		op.chart[i][0] = c
+	}
+
+	for ln := 1; ln < n; ln++ {
+		for i := 0; i+ln < n; i++ {
+			c := make(cell)
+			for split := 0; split < ln; split++ {
+				lc, rc := op.chart[i][split], op.chart[i+split+1][ln-split-1]
+				for _, r := range op.binary {
+					les, res := lc[r.rhs[0]], rc[r.rhs[1]]
+					for _, le := range les {
+						for _, re := range res {
+							op.insert(c, &entry{
+								sym:   r.lhs,
+								score: r.score + le.score + re.score,
+								rule:  r,
+								left:  le,
+								right: re,
+							})
+						}
+					}
+				}
+			}
+			op.unary(c)
+			op.chart[i][ln] = c
+		}
+	}
+}
+
+// unary applies the unary rules in a cell until nothing changes.
+func (op *parseOp) unary(c cell) {
+	type key struct {
+		r *rule
+		e *entry
+	}
+	used := make(map[key]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, r := range op.unaries {
+			for _, e := range c[r.rhs[0]] {
+				if used[key{r, e}] || e.sym == r.lhs || inChain(e.chain, r.lhs) {
+					continue
+				}
+				used[key{r, e}] = true
+				ue := &entry{
+					sym:   r.lhs,
+					score: r.score + e.score,
+					rule:  r,
+					left:  e,
+					chain: append([]int{e.sym}, e.chain...),
+				}
+				if op.insert(c, ue) {
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+func inChain(chain []int, sym int) bool {
+	for _, s := range chain {
+		if s == sym {
+			return true
+		}
+	}
+	return false
+}
+
+// build returns the nodes for an entry. A non-terminal from the grammar
+// produces a single node, a symbol added by the conversion produces the nodes
+// that are spliced into it's parent.
+func (op *parseOp) build(e *entry) []*tree.PN {
+	var children [][]*tree.PN
+	switch {
+	case e.left == nil:
+		children = [][]*tree.PN{{{Lexeme: lexeme.Copy(op.lxms[e.pos])}}}
+	case e.right == nil:
+		children = [][]*tree.PN{op.build(e.left)}
+	default:
+		children = [][]*tree.PN{op.build(e.left), op.build(e.right)}
+	}
+	return e.rule.apply(children, op.compiled)
+}
+
+func newNode(kind string, children []*tree.PN) *tree.PN {
+	lx := lexeme.New(stringsymbol.Symbol(kind))
+	pn := &tree.PN{
+		Lexeme: lx,
+		C:      children,
+	}
+	for _, c := range children {
+		c.P = pn
+		lx.Span(c.Lexeme)
+	}
+	return pn
+}
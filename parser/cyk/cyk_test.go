@@ -0,0 +1,143 @@
+package cyk
+
+import (
+	"math"
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/grammar"
+	"github.com/adamcolton/parlex/lexer/simplelexer"
+	"github.com/adamcolton/parlex/parser/action"
+	"github.com/adamcolton/parlex/parser/packrat"
+	"github.com/adamcolton/parlex/tree"
+	"github.com/stretchr/testify/assert"
+)
+
+var lxr = parlex.MustLexer(simplelexer.New(`
+  ( /\(/
+  ) /\)/
+  [ /\[/
+  ] /\]/
+  , /,/
+  op /[+\-\*\/]/
+  int /\d+/
+  word /[a-z]+/
+  space /\s+/ -
+`))
+
+func TestMatchesPackrat(t *testing.T) {
+	tt := map[string][]string{
+		`
+      E -> T op E
+        -> T
+      T -> ( E )
+        -> int
+    `: {"1", "1+2", "1+(2*3)-4", "((1))"},
+		`
+      E -> E op T
+        -> T
+      T -> ( E )
+        -> int
+    `: {"1", "1+2-3", "(1+2)*3"},
+		`
+      A    -> [ Vals ]
+      Vals -> int More
+           ->
+      More -> , int More
+           ->
+    `: {"[]", "[1]", "[1,2,3]"},
+	}
+	for g, inputs := range tt {
+		grmr, err := grammar.New(g)
+		assert.NoError(t, err)
+		c := New(grmr)
+		p := packrat.New(grmr)
+		for _, s := range inputs {
+			lxms := lxr.Lex(s)
+			expected := p.Parse(lxms)
+			got := c.Parse(lxms)
+			if assert.NotNil(t, got, s) {
+				assert.True(t, tree.Equal(expected, got), s)
+				assert.Equal(t, expected.(*tree.PN).String(), got.(*tree.PN).String(), s)
+			}
+		}
+		assert.Nil(t, c.Parse(lxr.Lex("1 1 )")))
+	}
+}
+
+func TestKBest(t *testing.T) {
+	grmr, err := grammar.New(`
+    E -> E op E
+      -> int
+  `)
+	assert.NoError(t, err)
+	c := New(grmr)
+	lxms := lxr.Lex("1+2*3")
+
+	ps := c.KBest(lxms, 5)
+	if assert.Len(t, ps, 2) {
+		// both trees use E -> E op E twice and E -> int 3 times
+		expected := 5 * math.Log(0.5)
+		assert.InDelta(t, expected, ps[0].Score, 1e-9)
+		assert.InDelta(t, expected, ps[1].Score, 1e-9)
+		assert.InDelta(t, math.Pow(0.5, 5), ps[0].Prob(), 1e-9)
+		assert.NotEqual(t, ps[0].Tree.String(), ps[1].Tree.String())
+	}
+
+	assert.Len(t, c.KBest(lxr.Lex("1+2+3+4"), 10), 5)
+	assert.Len(t, c.KBest(lxr.Lex("1+2+3+4"), 3), 3)
+}
+
+func TestWeights(t *testing.T) {
+	grmr, err := grammar.New(`
+    Cmd    -> word Target
+    Target -> Thing
+           -> Person
+    Thing  -> word
+    Person -> word
+  `)
+	assert.NoError(t, err)
+	c := New(grmr)
+	lxms := lxr.Lex("call bob")
+
+	assert.NoError(t, c.WeightRule("Target -> Person", 3))
+	best, ok := c.Best(lxms)
+	assert.True(t, ok)
+	assert.Equal(t, "Person", best.Tree.C[1].C[0].Kind().String())
+	assert.InDelta(t, 0.75, best.Prob(), 1e-9)
+
+	c.Weight("Target", 1, 1).Weight("Target", 0, 9)
+	best, _ = c.Best(lxms)
+	assert.Equal(t, "Thing", best.Tree.C[1].C[0].Kind().String())
+	assert.InDelta(t, 0.9, best.Prob(), 1e-9)
+
+	c.Weight("Target", 0, 0)
+	ps := c.KBest(lxms, 2)
+	assert.Len(t, ps, 1)
+
+	assert.Equal(t, action.ErrNoProduction, c.WeightRule("Target -> word", 1))
+}
+
+func TestEmpty(t *testing.T) {
+	grmr, err := grammar.New(`
+    S -> A B
+    A -> a
+      ->
+    B -> b
+      ->
+  `)
+	assert.NoError(t, err)
+	c := New(grmr)
+	best, ok := c.Best(nil)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.25, best.Prob(), 1e-9)
+	expected, _ := tree.New(`
+    S {
+      A
+      B
+    }
+  `)
+	assert.Equal(t, expected.String(), best.Tree.String())
+
+	assert.Nil(t, New(grammar.Empty()).Parse(nil))
+}
@@ -0,0 +1,21 @@
+// Package cyk implements a CYK chart parser with probabilistic scoring.
+//
+// The grammar is converted internally to a form close to Chomsky normal form:
+// long productions are split, terminals in long productions get their own
+// symbols and nullable symbols are removed by adding a rule without them. Unary
+// rules are kept and closed over in each cell of the chart. Each converted rule
+// remembers how to rebuild the part of the tree it came from, so the trees that
+// are returned have the shape of the original grammar.
+//
+// Productions can be weighted. The weights of a non-terminal's productions are
+// normalized into probabilities and a parse is scored by the log of the
+// product of the probabilities of the productions it uses. Parse returns the
+// most probable (Viterbi) tree and KBest returns the k most probable trees with
+// their scores, which is useful for ambiguous input where ranking matters.
+//
+//	c := cyk.New(grmr)
+//	c.WeightRule("Target -> Person", 3)
+//	for _, p := range c.KBest(lexemes, 3) {
+//	  fmt.Println(p.Prob(), p.Tree)
+//	}
+package cyk
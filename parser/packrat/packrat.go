@@ -1,11 +1,15 @@
 // Package packrat implements a packrat parser based on
 // http://web.cs.ucla.edu/~todd/research/pepm08.pdf . It can handle left
-// recursion.
+// recursion. By default a grammar is treated as context free and the priority
+// of the productions decides between trees, calling PEG switches to the
+// ordered choice of a parsing expression grammar.
 package packrat
 
 import (
 	"github.com/adamcolton/parlex"
 	"github.com/adamcolton/parlex/lexeme"
+	"github.com/adamcolton/parlex/parser/action"
+	"github.com/adamcolton/parlex/parser/profile"
 	"github.com/adamcolton/parlex/symbol/setsymbol"
 	"github.com/adamcolton/parlex/tree"
 )
@@ -13,6 +17,9 @@ import (
 // Packrat is a Packrat parser
 type Packrat struct {
 	parlex.Grammar
+	profiler *profile.Profiler
+	embedded map[string]parlex.PrefixParser
+	peg      bool
 }
 
 type treeMarker struct {
@@ -55,6 +62,20 @@ type prOp struct {
 	nonterms []bool
 	stack    *updater
 	set      *setsymbol.Set
+	rec      *profile.Recorder
+	raw      []parlex.Lexeme
+	// embedded maps non-terminals to the parsers that replace their
+	// productions and trees holds the trees they returned
+	embedded map[int]parlex.PrefixParser
+	trees    map[treeKey]parlex.ParseNode
+	// operators maps operator productions to the position of their operator
+	operators map[opKey]int
+	// predicates maps predicate symbols to their targets and looked caches
+	// the result of running a predicate's target
+	predicates map[int]predicate
+	looked     map[treeMarker]bool
+	// matcher is set if the grammar fulfills parlex.TerminalMatcher
+	matcher parlex.TerminalMatcher
 }
 
 // New returns a Packrat parser
@@ -71,15 +92,77 @@ func Constructor(grmr parlex.Grammar) (parlex.Parser, error) {
 	}, nil
 }
 
+// Instrument sets the Profiler that will record statistics for each parse. A
+// nil Profiler turns off profiling.
+func (p *Packrat) Instrument(profiler *profile.Profiler) *Packrat {
+	p.profiler = profiler
+	return p
+}
+
+// PEG sets the parser to use the semantics of a parsing expression grammar.
+// The productions of a non-terminal are an ordered choice; the first
+// production that matches is used and the rest are never tried at that
+// position, even if a symbol after the non-terminal then fails. So each
+// non-terminal has at most one tree at a position and a grammar written for
+// another PEG tool will accept the same input. Left recursion is still
+// supported by growing the seed of the recursion, as described in the paper.
+// Operator precedence declarations are ignored.
+func (p *Packrat) PEG() *Packrat {
+	p.peg = true
+	return p
+}
+
+// PEGConstructor fulfills parlex.ParserConstructor, the Parser uses PEG
+// semantics.
+func PEGConstructor(grmr parlex.Grammar) (parlex.Parser, error) {
+	return New(grmr).PEG(), nil
+}
+
+// Embed sets a parser to use for a non-terminal in place of it's productions.
+// The parser is given the lexemes from where the non-terminal is needed and the
+// tree it returns is used as the tree of the non-terminal. This allows a
+// specialized parser, like a Pratt parser for expressions, to handle part of
+// the grammar. The tree returned should have the non-terminal as it's kind.
+func (p *Packrat) Embed(nonterminal string, parser parlex.PrefixParser) *Packrat {
+	if p.embedded == nil {
+		p.embedded = make(map[string]parlex.PrefixParser)
+	}
+	p.embedded[nonterminal] = parser
+	return p
+}
+
 // Parse fulfills the parlex.Parser. The Packrat parser will try to parse the
 // lexemes.
 func (p *Packrat) Parse(lexemes []parlex.Lexeme) parlex.ParseNode {
+	if pn := p.parse(lexemes, nil); pn != nil {
+		return pn
+	}
+	return nil
+}
+
+// ParseActions parses the lexemes and then runs the actions bottom up over the
+// accepted derivation. It returns the value from the action of the root along
+// with the parse tree. If the parse fails, both will be nil.
+func (p *Packrat) ParseActions(lexemes []parlex.Lexeme, actions *action.Actions) (interface{}, parlex.ParseNode) {
+	d := make(action.Derivation)
+	pn := p.parse(lexemes, d)
+	if pn == nil {
+		return nil, nil
+	}
+	return actions.Eval(pn, d.Production), pn
+}
+
+func (p *Packrat) parse(lexemes []parlex.Lexeme, d action.Derivation) *tree.PN {
 	nts := p.Grammar.NonTerminals()
 	if len(nts) == 0 {
 		return nil
 	}
 	set := setsymbol.New()
 	set.LoadGrammar(p.Grammar)
+	embedded := make(map[int]parlex.PrefixParser, len(p.embedded))
+	for nonterm, parser := range p.embedded {
+		embedded[set.Str(nonterm).Idx()] = parser
+	}
 	op := &prOp{
 		grmr:     p.Grammar,
 		lxms:     set.LoadLexemes(lexemes),
@@ -89,21 +172,32 @@ func (p *Packrat) Parse(lexemes []parlex.Lexeme) parlex.ParseNode {
 		queued:   make(map[treeMarker]bool),
 		set:      set,
 		nonterms: make([]bool, set.Size()),
+		rec:      p.profiler.Start(),
+		raw:      lexemes,
+		embedded: embedded,
+		trees:    make(map[treeKey]parlex.ParseNode),
+		looked:   make(map[treeMarker]bool),
 	}
+	defer op.rec.Done()
 	for _, nonterm := range p.Grammar.NonTerminals() {
 		op.nonterms[op.set.Symbol(nonterm).Idx()] = true
 	}
+	for idx := range op.embedded {
+		op.nonterms[idx] = true
+	}
+	op.matcher, _ = p.Grammar.(parlex.TerminalMatcher)
+	op.loadPredicates()
 
 	start := treeMarker{
 		idx: op.set.Symbol(nts[0]).Idx(),
 	}
-	op.addProds(start)
-
-	var u *updater
-	for op.stack != nil {
-		u, op.stack = op.stack, op.stack.next
-		u.update(op)
+	if p.peg {
+		return op.parsePEG(start, d)
 	}
+	op.loadOperators()
+	op.addProds(start)
+	op.run()
+	op.rec.MemoSize(len(op.memo))
 
 	var accept treeKey
 	accept.idx = start.idx
@@ -112,20 +206,54 @@ func (p *Packrat) Parse(lexemes []parlex.Lexeme) parlex.ParseNode {
 	if accepted.end != accept.end {
 		return nil
 	}
-	return accepted.toPN(op.lxms, op.memo, op.set)
+	return accepted.toPN(op, d)
+}
+
+// run processes the updaters until there are none left.
+func (op *prOp) run() {
+	var u *updater
+	for op.stack != nil {
+		u, op.stack = op.stack, op.stack.next
+		if op.rec != nil {
+			op.rec.Enter(op.name(u.base.idx))
+			u.update(op)
+			op.rec.Exit()
+		} else {
+			u.update(op)
+		}
+	}
+}
+
+func (op *prOp) name(idx int) string {
+	return op.set.ByIdx(idx).String()
 }
 
 func (op *prOp) addProds(root treeMarker) {
 	if op.queued[root] {
+		if op.rec != nil && op.nonterms[root.idx] {
+			op.rec.Memo(op.name(root.idx), true)
+		}
 		return
 	}
 	op.queued[root] = true
+	if parser, ok := op.embedded[root.idx]; ok {
+		op.embed(root, parser)
+		return
+	}
 	rootSymbol := op.set.ByIdx(root.idx)
 	prods := op.grmr.Productions(rootSymbol)
 	if prods == nil {
 		return
 	}
+	var name string
+	if op.rec != nil {
+		name = rootSymbol.String()
+		op.rec.Memo(name, false)
+		op.rec.Enter(name)
+		defer op.rec.Exit()
+	}
 	for i := prods.Iter(); i.Next(); {
+		op.rec.Attempt(name, i.Idx)
 		if i.Symbols() == 0 {
 			var nilTreeDef treeDef
 			nilTreeDef.treeMarker = root
@@ -178,7 +306,13 @@ func (u *updater) update(op *prOp) {
 }
 
 func (op *prOp) addToMemo(td treeDef) {
+	if op.violatesPrecedence(td) {
+		return
+	}
 	old, ok := op.memo[td.treeKey]
+	if op.rec != nil && op.nonterms[td.idx] && (!ok || td.priority < old.priority) {
+		op.rec.Accept(op.name(td.idx), td.priority)
+	}
 	if !ok {
 		op.memo[td.treeKey] = td
 		op.markers[td.treeMarker] = append(op.markers[td.treeMarker], td)
@@ -190,6 +324,18 @@ func (op *prOp) addToMemo(td treeDef) {
 	}
 }
 
+func (op *prOp) embed(root treeMarker, parser parlex.PrefixParser) {
+	pn, n := parser.ParsePrefix(op.raw[root.start:])
+	if pn == nil {
+		return
+	}
+	var td treeDef
+	td.treeMarker = root
+	td.end = root.start + n
+	op.trees[td.treeKey] = pn
+	op.addToMemo(td)
+}
+
 func (op *prOp) createsCircularDep(node treeDef, root *treeDef) bool {
 	for _, ck := range node.children {
 		if ck == root.treeKey || op.createsCircularDep(op.memo[ck], root) {
@@ -241,13 +387,20 @@ func (op *prOp) addPartial(tp treePartial, requires treeMarker) {
 }
 
 func (op *prOp) checkNonTerminal(at treeMarker) *treeDef {
+	if p, ok := op.predicates[at.idx]; ok {
+		return op.checkPredicate(at, p)
+	}
+	n := 1
 	matchesNonterminal := at.start < len(op.lxms) && at.idx == op.lxms[at.start].K.(*setsymbol.Symbol).Idx()
+	if !matchesNonterminal && op.matcher != nil && !op.nonterms[at.idx] {
+		n, matchesNonterminal = op.matcher.MatchTerminal(op.set.ByIdx(at.idx), op.raw[at.start:])
+	}
 	if !matchesNonterminal {
 		return nil
 	}
 	var td treeDef
 	td.treeMarker = at
-	td.end = at.start + 1
+	td.end = at.start + n
 	op.addToMemo(td)
 	return &td
 }
@@ -260,13 +413,24 @@ func (op *prOp) push(tp treePartial, tk treeKey) {
 	}
 }
 
-func (td *treeDef) toPN(lxms []*lexeme.Lexeme, memo map[treeKey]treeDef, set *setsymbol.Set) *tree.PN {
+func (td *treeDef) toPN(op *prOp, d action.Derivation) *tree.PN {
+	if pn, ok := op.trees[td.treeKey]; ok {
+		return tree.Clone(pn)
+	}
+	lxms := op.lxms
 	var lx *lexeme.Lexeme
 	var setPos bool
 	if td.start < len(lxms) && lxms[td.start].K.(*setsymbol.Symbol).Idx() == td.idx {
 		lx = lxms[td.start]
+	} else if !op.nonterms[td.idx] && td.end > td.start {
+		// a terminal matched by the grammar takes the values of the lexemes
+		lx = lexeme.New(op.set.ByIdx(td.idx))
+		for _, l := range op.raw[td.start:td.end] {
+			lx.V += l.Value()
+			lx.Span(l)
+		}
 	} else {
-		lx = lexeme.New(set.ByIdx(td.idx))
+		lx = lexeme.New(op.set.ByIdx(td.idx))
 		setPos = true
 	}
 	pn := &tree.PN{
@@ -274,13 +438,18 @@ func (td *treeDef) toPN(lxms []*lexeme.Lexeme, memo map[treeKey]treeDef, set *se
 		C:      make([]*tree.PN, len(td.children)),
 	}
 	for i, c := range td.children {
-		ct := memo[c]
-		cpn := ct.toPN(lxms, memo, set)
+		ct := op.memo[c]
+		cpn := ct.toPN(op, d)
 		cpn.P = pn
 		pn.C[i] = cpn
 	}
-	if setPos && len(pn.C) > 0 {
-		lx.L, lx.C = pn.C[0].Pos()
+	if setPos {
+		for _, c := range pn.C {
+			lx.Span(c.Lexeme)
+		}
+	}
+	if setPos && d != nil {
+		d[pn] = td.priority
 	}
 	return pn
 }
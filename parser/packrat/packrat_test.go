@@ -3,9 +3,13 @@ package packrat
 import (
 	"github.com/adamcolton/parlex"
 	"github.com/adamcolton/parlex/grammar"
+	"github.com/adamcolton/parlex/lexeme"
 	"github.com/adamcolton/parlex/lexer/simplelexer"
+	"github.com/adamcolton/parlex/parser/action"
+	"github.com/adamcolton/parlex/parser/profile"
 	"github.com/adamcolton/parlex/tree"
 	"github.com/stretchr/testify/assert"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -339,3 +343,428 @@ func TestConstructor(t *testing.T) {
 	pc = Constructor
 	assert.NotNil(t, pc)
 }
+
+func TestCST(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    ( /\(/
+    ) /\)/
+    op /[+\-\*\/]/
+    int /\d+/
+    space /\s+/ -
+  `)
+	assert.NoError(t, err)
+	grmr, err := grammar.New(`
+    E -> E op E
+      -> ( E )
+      -> int
+  `)
+	assert.NoError(t, err)
+
+	input := " ( 1+ 2 )*3 "
+	lxr.KeepTrivia()
+	pn, err := tree.ParseCST(input, lxr, New(grmr))
+	assert.NoError(t, err)
+	assert.Equal(t, input, tree.Print(pn))
+}
+
+func TestActions(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    ( /\(/
+    ) /\)/
+    op /[+\-\*\/]/
+    int /\d+/
+    space /\s+/ -
+  `)
+	assert.NoError(t, err)
+	grmr, err := grammar.New(`
+    E -> E op E
+      -> ( E )
+      -> int
+  `)
+	assert.NoError(t, err)
+
+	value := func(arg interface{}) int {
+		i, _ := strconv.Atoi(arg.(parlex.Lexeme).Value())
+		return i
+	}
+	actions := action.New().
+		MustAddRule(grmr, "E -> E op E", func(node parlex.ParseNode, args []interface{}) interface{} {
+			a, b := args[0].(int), args[2].(int)
+			switch args[1].(parlex.Lexeme).Value() {
+			case "+":
+				return a + b
+			case "-":
+				return a - b
+			case "/":
+				return a / b
+			}
+			return a * b
+		}).
+		MustAddRule(grmr, "E -> ( E )", func(node parlex.ParseNode, args []interface{}) interface{} {
+			return args[1]
+		}).
+		MustAddRule(grmr, "E -> int", func(node parlex.ParseNode, args []interface{}) interface{} {
+			return value(args[0])
+		})
+
+	p := New(grmr)
+	v, pn := p.ParseActions(lxr.Lex("5*(1+2)*3"), actions)
+	assert.Equal(t, 45, v)
+	assert.NotNil(t, pn)
+
+	v, pn = p.ParseActions(lxr.Lex("5*("), actions)
+	assert.Nil(t, v)
+	assert.Nil(t, pn)
+}
+
+func TestSpans(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    ( /\(/
+    ) /\)/
+    op /[+\-\*\/]/
+    int /\d+/
+    space /\s+/ -
+  `)
+	assert.NoError(t, err)
+	grmr, err := grammar.New(`
+    E -> E op E
+      -> ( E )
+      -> int
+  `)
+	assert.NoError(t, err)
+
+	pn := New(grmr).Parse(lxr.Lex("5 * (1 +\n 22)")).(*tree.PN)
+	span := func(node parlex.ParseNode) [4]int {
+		l, c := node.Pos()
+		el, ec, _ := parlex.End(node)
+		return [4]int{l, c, el, ec}
+	}
+	assert.Equal(t, [4]int{0, 1, 1, 5}, span(pn))
+	paren := pn.C[2]
+	assert.Equal(t, [4]int{0, 5, 1, 5}, span(paren))
+	assert.Equal(t, [4]int{0, 6, 1, 4}, span(paren.C[1]))
+
+	r := tree.Reducer{
+		"E": tree.If(tree.ChildIs(0, "("), tree.ReplaceWithChild(1), nil).
+			If(tree.ChildIs(1, "op"), tree.PromoteChildValue(1), nil).
+			PromoteSingleChild(),
+	}
+	reduced := r.RawReduce(pn)
+	assert.Equal(t, "*", reduced.Value())
+	assert.Equal(t, [4]int{0, 1, 1, 5}, span(reduced))
+	plus := reduced.C[1]
+	assert.Equal(t, "+", plus.Value())
+	assert.Equal(t, [4]int{0, 5, 1, 5}, span(plus))
+	assert.Equal(t, [4]int{1, 2, 1, 4}, span(plus.C[1]))
+}
+
+func TestInstrument(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    ( /\(/
+    ) /\)/
+    op /[+\-\*\/]/
+    int /\d+/
+    space /\s+/ -
+  `)
+	assert.NoError(t, err)
+	grmr, err := grammar.New(`
+    E -> T op E
+      -> T
+    T -> ( E )
+      -> int
+  `)
+	assert.NoError(t, err)
+
+	prof := profile.New()
+	prof.Labels = true
+	p := New(grmr).Instrument(prof)
+	assert.NotNil(t, p.Parse(lxr.Lex("1+(2+3)")))
+	assert.NotNil(t, p.Parse(lxr.Lex("4")))
+
+	rep := prof.Report()
+	assert.Equal(t, 2, rep.Parses)
+	assert.True(t, rep.MaxMemo > 0)
+	e, ok := rep.NonTerminal("E")
+	if assert.True(t, ok) {
+		assert.True(t, e.Attempts > 0)
+		assert.True(t, e.Productions[0].Accepts > 0)
+	}
+	tnt, ok := rep.NonTerminal("T")
+	if assert.True(t, ok) {
+		assert.True(t, tnt.Productions[0].Accepts > 0)
+		assert.True(t, tnt.Productions[1].Accepts > 0)
+		assert.True(t, tnt.MemoMisses > 0)
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    op /==|[+\-\*\/\^]/
+    int /\d+/
+    space /\s+/ -
+  `)
+	assert.NoError(t, err)
+	grmr, err := grammar.New(`
+    %nonassoc ==
+    %left + -
+    %left * /
+    %right ^
+    E -> E op E
+      -> int
+  `)
+	assert.NoError(t, err)
+	p := New(grmr)
+
+	var group func(parlex.ParseNode) string
+	group = func(pn parlex.ParseNode) string {
+		if pn.Children() == 1 {
+			return pn.Child(0).Value()
+		}
+		return "(" + group(pn.Child(0)) + pn.Child(1).Value() + group(pn.Child(2)) + ")"
+	}
+
+	tt := map[string]string{
+		"1+2*3":  "(1+(2*3))",
+		"1*2+3":  "((1*2)+3)",
+		"1-2-3":  "((1-2)-3)",
+		"8/4/2":  "((8/4)/2)",
+		"2^3^2":  "(2^(3^2))",
+		"2^3*4":  "((2^3)*4)",
+		"1+2==3": "((1+2)==3)",
+		"1==2+3": "(1==(2+3))",
+	}
+	for str, expected := range tt {
+		t.Run(str, func(t *testing.T) {
+			pn := p.Parse(lxr.Lex(str))
+			if assert.NotNil(t, pn) {
+				assert.Equal(t, expected, group(pn))
+			}
+		})
+	}
+
+	assert.Nil(t, p.Parse(lxr.Lex("1==2==3")))
+}
+
+type prefixParser func([]parlex.Lexeme) (parlex.ParseNode, int)
+
+func (fn prefixParser) ParsePrefix(lxms []parlex.Lexeme) (parlex.ParseNode, int) { return fn(lxms) }
+
+func TestEmbed(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    ; /;/
+    = /=/
+    id /[a-z]+/
+    int /\d+/
+    space /\s+/ -
+  `)
+	assert.NoError(t, err)
+	grmr, err := grammar.New(`
+    Stmts -> Stmt Stmts
+          ->
+    Stmt  -> id = E ;
+  `)
+	assert.NoError(t, err)
+
+	// E is any run of ints
+	ints := prefixParser(func(lxms []parlex.Lexeme) (parlex.ParseNode, int) {
+		pn := &tree.PN{Lexeme: lexeme.String("E")}
+		for _, lx := range lxms {
+			if lx.Kind().String() != "int" {
+				break
+			}
+			pn.C = append(pn.C, &tree.PN{Lexeme: lexeme.Copy(lx), P: pn})
+		}
+		if len(pn.C) == 0 {
+			return nil, 0
+		}
+		return pn, len(pn.C)
+	})
+
+	p := New(grmr).Embed("E", ints)
+	pn := p.Parse(lxr.Lex("a = 1 2; b = 3;"))
+	expected, _ := tree.New(`
+    Stmts {
+      Stmt {
+        id: "a"
+        =: "="
+        E {
+          int: "1"
+          int: "2"
+        }
+        ;: ";"
+      }
+      Stmts {
+        Stmt {
+          id: "b"
+          =: "="
+          E {
+            int: "3"
+          }
+          ;: ";"
+        }
+        Stmts
+      }
+    }
+  `)
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, expected.String(), pn.(*tree.PN).String())
+	}
+
+	assert.Nil(t, p.Parse(lxr.Lex("a = ;")))
+}
+
+func TestPredicates(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    a
+    b
+    c
+    space /\s+/ -
+  `)
+	assert.NoError(t, err)
+
+	and := New(parlex.MustGrammar(grammar.New(`
+    S  -> &AB A
+    A  -> a b
+       -> a c
+    AB -> a b
+  `)))
+	pn := and.Parse(lxr.Lex("a b"))
+	expected, _ := tree.New(`
+    S {
+      &AB
+      A {
+        a: "a"
+        b: "b"
+      }
+    }
+  `)
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, expected.String(), tree.Clone(pn).String())
+	}
+	assert.Nil(t, and.Parse(lxr.Lex("a c")))
+
+	not := New(parlex.MustGrammar(grammar.New(`
+    S  -> !AB A
+    A  -> a b
+       -> a c
+    AB -> a b
+  `)))
+	assert.NotNil(t, not.Parse(lxr.Lex("a c")))
+	assert.Nil(t, not.Parse(lxr.Lex("a b")))
+
+	// a predicate in the middle of a production
+	mid := New(parlex.MustGrammar(grammar.New(`
+    S -> a !C Bs
+    Bs -> b Bs
+       -> c
+       ->
+    C -> c
+  `)))
+	assert.NotNil(t, mid.Parse(lxr.Lex("a b b c")))
+	assert.Nil(t, mid.Parse(lxr.Lex("a c")))
+}
+
+func TestPEG(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    a
+    b
+    c
+    space /\s+/ -
+  `)
+	assert.NoError(t, err)
+
+	// The first production of A matches, so the second is never tried and S
+	// fails.
+	grmr := parlex.MustGrammar(grammar.New(`
+    S -> A c
+    A -> a
+      -> a b
+  `))
+	lxms := lxr.Lex("a b c")
+	assert.NotNil(t, New(grmr).Parse(lxms))
+	assert.Nil(t, New(grmr).PEG().Parse(lxms))
+
+	grmr = parlex.MustGrammar(grammar.New(`
+    S -> A c
+    A -> a b
+      -> a
+  `))
+	assert.NotNil(t, New(grmr).PEG().Parse(lxms))
+	assert.NotNil(t, New(grmr).PEG().Parse(lxr.Lex("a c")))
+
+	prsr, err := PEGConstructor(parlex.MustGrammar(grammar.New(`
+    S -> !B a
+    B -> a b
+  `)))
+	assert.NoError(t, err)
+	expected, _ := tree.New(`
+    S {
+      !B
+      a: "a"
+    }
+  `)
+	pn := prsr.Parse(lxr.Lex("a"))
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, expected.String(), tree.Clone(pn).String())
+	}
+}
+
+func TestPEGLeftRecursion(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    ( /\(/
+    ) /\)/
+    + /\+/
+    * /\*/
+    int /\d+/
+    space /\s+/ -
+  `)
+	assert.NoError(t, err)
+	grmr, err := grammar.New(`
+    E -> E + T
+      -> T
+    T -> T * F
+      -> F
+    F -> ( E )
+      -> int
+  `)
+	assert.NoError(t, err)
+
+	rdcr := tree.Reducer{
+		"E": tree.PromoteSingleChild,
+		"T": tree.PromoteSingleChild,
+		"F": tree.RemoveAll("(", ")").PromoteSingleChild(),
+	}
+	pn := rdcr.Reduce(New(grmr).PEG().Parse(lxr.Lex("1 + 2 * (3 + 4) + 5")))
+	expected, _ := tree.New(`
+    E {
+      E {
+        int: "1"
+        +: "+"
+        T {
+          int: "2"
+          *: "*"
+          E {
+            int: "3"
+            +: "+"
+            int: "4"
+          }
+        }
+      }
+      +: "+"
+      int: "5"
+    }
+  `)
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, expected.String(), pn.(*tree.PN).String())
+	}
+
+	// indirect left recursion
+	grmr, err = grammar.New(`
+    E -> A + int
+      -> int
+    A -> E
+  `)
+	assert.NoError(t, err)
+	pn = New(grmr).PEG().Parse(lxr.Lex("1 + 2 + 3"))
+	assert.NotNil(t, pn)
+}
@@ -0,0 +1,165 @@
+package packrat
+
+import (
+	"github.com/adamcolton/parlex/parser/action"
+	"github.com/adamcolton/parlex/tree"
+)
+
+// pegOp parses with PEG semantics. Each symbol has a single result at a
+// position, held in results. Trees are still kept in the memo so that toPN
+// can build the parse tree.
+type pegOp struct {
+	*prOp
+	results map[treeMarker]*pegResult
+	// active holds the non-terminals being evaluated, innermost last
+	active []*pegResult
+}
+
+type pegResult struct {
+	treeKey
+	ok bool
+	// evaluating is set while the productions are being tried. If the
+	// non-terminal is used again at the same position in that time, it is left
+	// recursive and recursed is set. Any non-terminal between the two uses is
+	// involved in the recursion and it's result is not kept.
+	evaluating, recursed, involved bool
+}
+
+func (op *prOp) parsePEG(start treeMarker, d action.Derivation) *tree.PN {
+	pop := &pegOp{
+		prOp:    op,
+		results: make(map[treeMarker]*pegResult),
+	}
+	tk, ok := pop.apply(start)
+	op.rec.MemoSize(len(op.memo))
+	if !ok || tk.end != len(op.lxms) {
+		return nil
+	}
+	accepted := op.memo[tk]
+	return accepted.toPN(op, d)
+}
+
+// apply returns the result of the symbol at the position.
+func (op *pegOp) apply(at treeMarker) (treeKey, bool) {
+	if r, ok := op.results[at]; ok {
+		if op.rec != nil && op.nonterms[at.idx] {
+			op.rec.Memo(op.name(at.idx), true)
+		}
+		if r.evaluating {
+			op.recurse(r)
+		}
+		return r.treeKey, r.ok
+	}
+	if p, ok := op.predicates[at.idx]; ok {
+		return op.predicate(at, p)
+	}
+	if parser, ok := op.embedded[at.idx]; ok {
+		op.embed(at, parser)
+		return op.keep(at, op.markers[at])
+	}
+	if !op.nonterms[at.idx] {
+		if td := op.checkNonTerminal(at); td != nil {
+			return op.keep(at, []treeDef{*td})
+		}
+		return op.keep(at, nil)
+	}
+
+	var name string
+	if op.rec != nil {
+		name = op.name(at.idx)
+		op.rec.Memo(name, false)
+		op.rec.Enter(name)
+		defer op.rec.Exit()
+	}
+	r := &pegResult{evaluating: true}
+	r.treeMarker = at
+	op.results[at] = r
+	op.active = append(op.active, r)
+
+	td, ok := op.choose(at, name)
+	for ok && r.recursed {
+		// grow the seed until it stops getting longer
+		r.treeKey, r.ok = td.treeKey, true
+		op.memo[td.treeKey] = td
+		grown, grew := op.choose(at, name)
+		if !grew || grown.end <= td.end {
+			break
+		}
+		td = grown
+	}
+
+	op.active = op.active[:len(op.active)-1]
+	r.evaluating = false
+	if r.treeKey, r.ok = td.treeKey, ok; ok {
+		op.memo[td.treeKey] = td
+		op.rec.Accept(name, td.priority)
+	}
+	if r.involved {
+		delete(op.results, at)
+	}
+	return r.treeKey, r.ok
+}
+
+// recurse marks head as left recursive and every non-terminal being evaluated
+// inside of it as involved.
+func (op *pegOp) recurse(head *pegResult) {
+	head.recursed = true
+	for i := len(op.active) - 1; i >= 0 && op.active[i] != head; i-- {
+		op.active[i].involved = true
+	}
+}
+
+// choose tries the productions in order and returns the first that matches.
+func (op *pegOp) choose(at treeMarker, name string) (treeDef, bool) {
+	prods := op.grmr.Productions(op.set.ByIdx(at.idx))
+	if prods == nil {
+		return treeDef{}, false
+	}
+	for i := prods.Iter(); i.Next(); {
+		op.rec.Attempt(name, i.Idx)
+		var td treeDef
+		td.treeMarker = at
+		td.end = at.start
+		td.priority = i.Idx
+		matched := true
+		for j := i.Iter(); matched && j.Next(); {
+			var ck treeKey
+			ck, matched = op.apply(treeMarker{
+				idx:   op.set.Symbol(j.Symbol).Idx(),
+				start: td.end,
+			})
+			td.children = append(td.children, ck)
+			td.end = ck.end
+		}
+		if matched {
+			return td, true
+		}
+	}
+	return treeDef{}, false
+}
+
+// predicate checks the target of the predicate without using any lexemes.
+func (op *pegOp) predicate(at treeMarker, p predicate) (treeKey, bool) {
+	if _, ok := op.apply(treeMarker{idx: p.target, start: at.start}); ok == p.not {
+		return op.keep(at, nil)
+	}
+	var td treeDef
+	td.treeMarker = at
+	td.end = at.start
+	op.memo[td.treeKey] = td
+	return op.keep(at, []treeDef{td})
+}
+
+// keep records the result of a symbol that is not evaluated from productions.
+// The first tree is the result.
+func (op *pegOp) keep(at treeMarker, tds []treeDef) (treeKey, bool) {
+	r := &pegResult{
+		ok: len(tds) > 0,
+	}
+	r.treeMarker = at
+	if r.ok {
+		r.treeKey = tds[0].treeKey
+	}
+	op.results[at] = r
+	return r.treeKey, r.ok
+}
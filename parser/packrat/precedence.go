@@ -0,0 +1,68 @@
+package packrat
+
+import (
+	"github.com/adamcolton/parlex"
+)
+
+// opKey identifies a production by the index of it's non-terminal and it's
+// priority.
+type opKey struct {
+	idx, priority int
+}
+
+// loadOperators finds the operator productions, those in the form
+// A -> A ... op ... A, if the grammar fulfills parlex.Precedencer. The
+// position of the first terminal between the recursive symbols is taken as the
+// operator.
+func (op *prOp) loadOperators() {
+	if _, ok := op.grmr.(parlex.Precedencer); !ok {
+		return
+	}
+	op.operators = make(map[opKey]int)
+	for _, nt := range op.grmr.NonTerminals() {
+		idx := op.set.Symbol(nt).Idx()
+		for i := op.grmr.Productions(nt).Iter(); i.Next(); {
+			ln := i.Symbols()
+			if ln < 3 || op.set.Symbol(i.Symbol(0)).Idx() != idx || op.set.Symbol(i.Symbol(ln-1)).Idx() != idx {
+				continue
+			}
+			for j := 1; j < ln-1; j++ {
+				if !op.nonterms[op.set.Symbol(i.Symbol(j)).Idx()] {
+					op.operators[opKey{idx, i.Idx}] = j
+					break
+				}
+			}
+		}
+	}
+}
+
+// operator returns the precedence of the operator in a tree. If the tree is not
+// from an operator production or the operator has no declared precedence, ok is
+// false.
+func (op *prOp) operator(td treeDef) (level int, assoc parlex.Assoc, ok bool) {
+	pos, isOp := op.operators[opKey{td.idx, td.priority}]
+	if !isOp || pos >= len(td.children) {
+		return 0, parlex.NonAssoc, false
+	}
+	return parlex.OperatorPrecedence(op.grmr, op.lxms[td.children[pos].start])
+}
+
+// violatesPrecedence returns true if either operand of an operator tree is an
+// operator tree that should have bound less tightly. An operand of the same
+// level is only allowed on the left of a left associative operator and on the
+// right of a right associative operator, so non-associative operators cannot
+// be chained.
+func (op *prOp) violatesPrecedence(td treeDef) bool {
+	if op.operators == nil {
+		return false
+	}
+	level, assoc, ok := op.operator(td)
+	if !ok {
+		return false
+	}
+	if l, _, ok := op.operator(op.memo[td.children[0]]); ok && (l < level || (l == level && assoc != parlex.LeftAssoc)) {
+		return true
+	}
+	l, _, ok := op.operator(op.memo[td.children[len(td.children)-1]])
+	return ok && (l < level || (l == level && assoc != parlex.RightAssoc))
+}
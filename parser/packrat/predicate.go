@@ -0,0 +1,81 @@
+package packrat
+
+import (
+	"github.com/adamcolton/parlex"
+)
+
+// predicate is a syntactic predicate on a non-terminal. A positive predicate
+// holds if the target matches at the position, a negative predicate holds if
+// it does not. Either way no lexemes are used.
+type predicate struct {
+	target int
+	not    bool
+}
+
+// loadPredicates finds the symbols that are predicates. A symbol is a
+// predicate if it is & or ! followed by the name of a non-terminal, like
+// "&Expr" or "!Keyword".
+func (op *prOp) loadPredicates() {
+	for idx := 0; idx < op.set.Size(); idx++ {
+		name := op.set.ByIdx(idx).String()
+		if len(name) < 2 || (name[0] != '&' && name[0] != '!') || !op.set.Has(name[1:]) {
+			continue
+		}
+		target := op.set.Str(name[1:]).Idx()
+		if target >= len(op.nonterms) || !op.nonterms[target] {
+			continue
+		}
+		if op.predicates == nil {
+			op.predicates = make(map[int]predicate)
+		}
+		op.predicates[idx] = predicate{
+			target: target,
+			not:    name[0] == '!',
+		}
+	}
+}
+
+// checkPredicate adds an empty tree for the predicate if it holds.
+func (op *prOp) checkPredicate(at treeMarker, p predicate) *treeDef {
+	if op.lookahead(treeMarker{idx: p.target, start: at.start}) == p.not {
+		return nil
+	}
+	var td treeDef
+	td.treeMarker = at
+	td.end = at.start
+	op.addToMemo(td)
+	return &td
+}
+
+// lookahead returns true if the non-terminal matches any of the lexemes
+// starting at the marker. It runs a separate parse so that a negative
+// predicate only fails once every way the target could match has been tried.
+// A predicate that depends on itself at the same position does not match.
+func (op *prOp) lookahead(at treeMarker) bool {
+	if m, ok := op.looked[at]; ok {
+		return m
+	}
+	op.looked[at] = false
+	sub := &prOp{
+		grmr:       op.grmr,
+		lxms:       op.lxms,
+		memo:       make(map[treeKey]treeDef),
+		markers:    make(map[treeMarker][]treeDef),
+		partials:   make(map[treeMarker][]treePartial),
+		queued:     make(map[treeMarker]bool),
+		set:        op.set,
+		nonterms:   op.nonterms,
+		raw:        op.raw,
+		embedded:   op.embedded,
+		trees:      make(map[treeKey]parlex.ParseNode),
+		operators:  op.operators,
+		predicates: op.predicates,
+		looked:     op.looked,
+		matcher:    op.matcher,
+	}
+	sub.addProds(at)
+	sub.run()
+	m := len(sub.markers[at]) > 0
+	op.looked[at] = m
+	return m
+}
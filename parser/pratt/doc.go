@@ -0,0 +1,20 @@
+// Package pratt implements a Pratt (top down operator precedence) parser for
+// expressions.
+//
+// Operators are given in a table keyed on lexeme kinds with a binding power and
+// associativity. Prefix, infix, postfix and mixfix operators like the ternary
+// are all described by a pattern of tokens and operands.
+//
+//	p := pratt.New("E").
+//	  Atom("int").
+//	  Group("(", ")").
+//	  Infix("+", 1, parlex.LeftAssoc).
+//	  Infix("*", 2, parlex.LeftAssoc).
+//	  Prefix("-", 3).
+//	  MustAdd(0, parlex.RightAssoc, pratt.Operand, "?", pratt.Operand, ":", pratt.Operand)
+//
+// A Pratt parser can be used on it's own as a parlex.Parser or embedded as the
+// parser for one non-terminal of a packrat grammar.
+//
+//	prsr := packrat.New(grmr).Embed("E", p)
+package pratt
@@ -0,0 +1,272 @@
+package pratt
+
+import (
+	"errors"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/lexeme"
+	"github.com/adamcolton/parlex/symbol/stringsymbol"
+	"github.com/adamcolton/parlex/tree"
+)
+
+// Operand marks the position of an operand in an operator pattern.
+const Operand = "_"
+
+// ErrBadPattern is returned when an operator pattern cannot be parsed. A
+// pattern needs at least two parts and cannot have two operands next to each
+// other.
+var ErrBadPattern = errors.New("Bad operator pattern")
+
+// Operator is an entry in the operator table. The Pattern is the sequence of
+// lexeme kinds with Operand marking the operands, so an infix operator is
+// {Operand, "+", Operand} and the ternary operator is
+// {Operand, "?", Operand, ":", Operand}. A token in a pattern matches a lexeme
+// with that kind or, if no kind matches, with that value.
+//
+// Operators with a higher Power bind tighter. Operands between two tokens are
+// full expressions. A trailing operand only takes operators that bind tighter,
+// unless the operator is right associative, in which case it also takes
+// operators of the same power. Non-associative operators of the same power
+// cannot be chained.
+type Operator struct {
+	Pattern []string
+	Power   int
+	Assoc   parlex.Assoc
+}
+
+// Pratt is a top down operator precedence parser for expressions. Every
+// expression is a node of kind Name and the children follow the operator
+// pattern, so the trees have the same shape as those from a grammar like
+//
+//	E -> E + E
+//	  -> - E
+//	  -> ( E )
+//	  -> int
+//
+// and the same reducers can be used.
+type Pratt struct {
+	Name  string
+	atoms map[string]bool
+	// nud holds operators that start with a token, led holds operators that
+	// start with an operand keyed by the token that follows it.
+	nud map[string]*Operator
+	led map[string]*Operator
+}
+
+// New returns a Pratt parser that will name expression nodes with name.
+func New(name string) *Pratt {
+	return &Pratt{
+		Name:  name,
+		atoms: make(map[string]bool),
+		nud:   make(map[string]*Operator),
+		led:   make(map[string]*Operator),
+	}
+}
+
+// Atom adds kinds of lexemes that are operands on their own, like numbers and
+// identifiers.
+func (p *Pratt) Atom(kinds ...string) *Pratt {
+	for _, k := range kinds {
+		p.atoms[k] = true
+	}
+	return p
+}
+
+// Add an operator to the table. An operator that starts with an operand
+// replaces any operator starting with an operand and the same token, an
+// operator that starts with a token replaces any that starts with the same
+// token.
+func (p *Pratt) Add(power int, assoc parlex.Assoc, pattern ...string) error {
+	if len(pattern) < 2 {
+		return ErrBadPattern
+	}
+	for i := 1; i < len(pattern); i++ {
+		if pattern[i] == Operand && pattern[i-1] == Operand {
+			return ErrBadPattern
+		}
+	}
+	o := &Operator{
+		Pattern: pattern,
+		Power:   power,
+		Assoc:   assoc,
+	}
+	if pattern[0] == Operand {
+		p.led[pattern[1]] = o
+	} else {
+		p.nud[pattern[0]] = o
+	}
+	return nil
+}
+
+// MustAdd calls Add and panics if there is an error.
+func (p *Pratt) MustAdd(power int, assoc parlex.Assoc, pattern ...string) *Pratt {
+	if err := p.Add(power, assoc, pattern...); err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Prefix adds a prefix operator like -x.
+func (p *Pratt) Prefix(kind string, power int) *Pratt {
+	return p.MustAdd(power, parlex.RightAssoc, kind, Operand)
+}
+
+// Infix adds a binary operator like x+y.
+func (p *Pratt) Infix(kind string, power int, assoc parlex.Assoc) *Pratt {
+	return p.MustAdd(power, assoc, Operand, kind, Operand)
+}
+
+// Postfix adds a postfix operator like x!.
+func (p *Pratt) Postfix(kind string, power int) *Pratt {
+	return p.MustAdd(power, parlex.LeftAssoc, Operand, kind)
+}
+
+// Group adds a bracketing operator like (x).
+func (p *Pratt) Group(open, close string) *Pratt {
+	return p.MustAdd(0, parlex.NonAssoc, open, Operand, close)
+}
+
+// Parse fulfills parlex.Parser. All of the lexemes must be used by the
+// expression.
+func (p *Pratt) Parse(lexemes []parlex.Lexeme) parlex.ParseNode {
+	pn, n := p.ParsePrefix(lexemes)
+	if pn == nil || n != len(lexemes) {
+		return nil
+	}
+	return pn
+}
+
+// ParsePrefix fulfills parlex.PrefixParser. It parses the longest expression
+// at the start of the lexemes. This allows a Pratt parser to be embedded in a
+// packrat parser.
+func (p *Pratt) ParsePrefix(lexemes []parlex.Lexeme) (parlex.ParseNode, int) {
+	op := &parseOp{
+		Pratt: p,
+		lxms:  lexemes,
+	}
+	pn := op.expr(0)
+	if pn == nil {
+		return nil, 0
+	}
+	return pn, op.pos
+}
+
+type parseOp struct {
+	*Pratt
+	lxms []parlex.Lexeme
+	pos  int
+}
+
+func lookup(table map[string]*Operator, lx parlex.Lexeme) *Operator {
+	if o, ok := table[lx.Kind().String()]; ok {
+		return o
+	}
+	return table[lx.Value()]
+}
+
+func matches(token string, lx parlex.Lexeme) bool {
+	return lx.Kind().String() == token || lx.Value() == token
+}
+
+// Binding powers are doubled so that associativity can be expressed by
+// adjusting the power of the trailing operand by one. They are also shifted up
+// by one so that an operator with a Power of 0 still binds tighter than the
+// start of an expression.
+func (o *Operator) lbp() int { return 2*o.Power + 2 }
+
+func (o *Operator) rbp() int {
+	if o.Assoc == parlex.RightAssoc {
+		return 2*o.Power + 1
+	}
+	return 2*o.Power + 3
+}
+
+// expr parses an expression that only takes infix and postfix operators with
+// a left binding power greater than min.
+func (op *parseOp) expr(min int) *tree.PN {
+	if op.pos >= len(op.lxms) {
+		return nil
+	}
+	lx := op.lxms[op.pos]
+	var left *tree.PN
+	if op.atoms[lx.Kind().String()] {
+		op.pos++
+		left = op.node([]*tree.PN{leaf(lx)})
+	} else if o := lookup(op.nud, lx); o != nil {
+		if left = op.apply(o, nil); left == nil {
+			return nil
+		}
+	} else {
+		return nil
+	}
+
+	var last *Operator
+	for op.pos < len(op.lxms) {
+		o := lookup(op.led, op.lxms[op.pos])
+		if o == nil || o.lbp() <= min {
+			break
+		}
+		if last != nil && o.Assoc == parlex.NonAssoc && last.Power == o.Power {
+			break
+		}
+		pn := op.apply(o, left)
+		if pn == nil {
+			break
+		}
+		left, last = pn, o
+	}
+	return left
+}
+
+// apply matches the rest of an operator pattern. If it fails, the position is
+// restored and nil is returned.
+func (op *parseOp) apply(o *Operator, left *tree.PN) *tree.PN {
+	start := op.pos
+	pattern := o.Pattern
+	var children []*tree.PN
+	if left != nil {
+		children = append(children, left)
+		pattern = pattern[1:]
+	}
+	for i, part := range pattern {
+		if part != Operand {
+			if op.pos >= len(op.lxms) || !matches(part, op.lxms[op.pos]) {
+				op.pos = start
+				return nil
+			}
+			children = append(children, leaf(op.lxms[op.pos]))
+			op.pos++
+			continue
+		}
+		min := 0
+		if i == len(pattern)-1 {
+			min = o.rbp()
+		}
+		c := op.expr(min)
+		if c == nil {
+			op.pos = start
+			return nil
+		}
+		children = append(children, c)
+	}
+	return op.node(children)
+}
+
+func leaf(lx parlex.Lexeme) *tree.PN {
+	return &tree.PN{
+		Lexeme: lexeme.Copy(lx),
+	}
+}
+
+func (op *parseOp) node(children []*tree.PN) *tree.PN {
+	lx := lexeme.New(stringsymbol.Symbol(op.Name))
+	pn := &tree.PN{
+		Lexeme: lx,
+		C:      children,
+	}
+	for _, c := range children {
+		c.P = pn
+		lx.Span(c.Lexeme)
+	}
+	return pn
+}
@@ -0,0 +1,174 @@
+package pratt
+
+import (
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/grammar"
+	"github.com/adamcolton/parlex/lexer/simplelexer"
+	"github.com/adamcolton/parlex/parser/packrat"
+	"github.com/adamcolton/parlex/tree"
+	"github.com/stretchr/testify/assert"
+)
+
+var lxr = parlex.MustLexer(simplelexer.New(`
+    op  /==|[+\-\*\/\^!?:]/
+    (   /\(/
+    )   /\)/
+    ;   /;/
+    int /\d+/
+    space /\s+/ -
+  `))
+
+func testParser() *Pratt {
+	return New("E").
+		Atom("int").
+		Group("(", ")").
+		MustAdd(1, parlex.RightAssoc, Operand, "?", Operand, ":", Operand).
+		Infix("==", 2, parlex.NonAssoc).
+		Infix("+", 3, parlex.LeftAssoc).
+		Infix("-", 3, parlex.LeftAssoc).
+		Infix("*", 4, parlex.LeftAssoc).
+		Infix("/", 4, parlex.LeftAssoc).
+		Prefix("-", 5).
+		Infix("^", 6, parlex.RightAssoc).
+		Postfix("!", 7)
+}
+
+// group renders a tree with parenthesis around each operator
+func group(pn parlex.ParseNode) string {
+	if pn.Children() == 1 {
+		return pn.Child(0).Value()
+	}
+	out := "("
+	for i := 0; i < pn.Children(); i++ {
+		c := pn.Child(i)
+		if c.Kind().String() == "E" {
+			out += group(c)
+		} else {
+			out += c.Value()
+		}
+	}
+	return out + ")"
+}
+
+func TestPowerZero(t *testing.T) {
+	p := New("E").
+		Atom("int").
+		MustAdd(0, parlex.RightAssoc, Operand, "?", Operand, ":", Operand).
+		Infix("+", 1, parlex.LeftAssoc)
+	tt := map[string]string{
+		"1?2:3":     "(1?2:3)",
+		"1?2:3?4:5": "(1?2:(3?4:5))",
+		"1+2?3:4+5": "((1+2)?3:(4+5))",
+	}
+	for str, expected := range tt {
+		lxms := lxr.Lex(str)
+		pn, n := p.ParsePrefix(lxms)
+		if assert.NotNil(t, pn, str) {
+			assert.Equal(t, expected, group(pn), str)
+			assert.Equal(t, len(lxms), n, str)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	p := testParser()
+	tt := map[string]string{
+		"1":           "1",
+		"1+2*3":       "(1+(2*3))",
+		"1*2+3":       "((1*2)+3)",
+		"1-2-3":       "((1-2)-3)",
+		"2^3^2":       "(2^(3^2))",
+		"-2^2":        "(-(2^2))",
+		"-2*3":        "((-2)*3)",
+		"3!^2":        "((3!)^2)",
+		"1--2":        "(1-(-2))",
+		"(1+2)*3":     "((((1+2)))*3)",
+		"1+2==3":      "((1+2)==3)",
+		"1?2:3":       "(1?2:3)",
+		"1?2:3?4:5":   "(1?2:(3?4:5))",
+		"1==2?3+4:5":  "((1==2)?(3+4):5)",
+		"1?2?3:4:5*6": "(1?(2?3:4):(5*6))",
+	}
+	for str, expected := range tt {
+		t.Run(str, func(t *testing.T) {
+			pn := p.Parse(lxr.Lex(str))
+			if assert.NotNil(t, pn) {
+				assert.Equal(t, expected, group(pn))
+			}
+		})
+	}
+
+	for _, str := range []string{"", "1==2==3", "1+", "(1+2", "1 2", "*1"} {
+		assert.Nil(t, p.Parse(lxr.Lex(str)), str)
+	}
+}
+
+func TestTree(t *testing.T) {
+	pn := testParser().Parse(lxr.Lex("1+-2"))
+	expected, err := tree.New(`
+    E {
+      E {
+        int: "1"
+      }
+      op: "+"
+      E {
+        op: "-"
+        E {
+          int: "2"
+        }
+      }
+    }
+  `)
+	assert.NoError(t, err)
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, expected.String(), pn.(*tree.PN).String())
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	p := testParser()
+	pn, n := p.ParsePrefix(lxr.Lex("1+2*3; 4"))
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, "(1+(2*3))", group(pn))
+	}
+	assert.Equal(t, 5, n)
+
+	// an operator without a right operand is left unused
+	pn, n = p.ParsePrefix(lxr.Lex("1+2+;"))
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, "(1+2)", group(pn))
+	}
+	assert.Equal(t, 3, n)
+
+	pn, n = p.ParsePrefix(lxr.Lex(";"))
+	assert.Nil(t, pn)
+	assert.Equal(t, 0, n)
+}
+
+func TestBadPattern(t *testing.T) {
+	p := New("E")
+	assert.Equal(t, ErrBadPattern, p.Add(1, parlex.LeftAssoc, "+"))
+	assert.Equal(t, ErrBadPattern, p.Add(1, parlex.LeftAssoc, Operand, Operand, "+"))
+	assert.Equal(t, ErrBadPattern, p.Add(1, parlex.LeftAssoc, "[", Operand, Operand, "]"))
+	assert.NoError(t, p.Add(1, parlex.LeftAssoc, Operand, "[", Operand, "]"))
+	assert.Panics(t, func() { p.MustAdd(1, parlex.LeftAssoc, Operand) })
+}
+
+func TestEmbed(t *testing.T) {
+	grmr, err := grammar.New(`
+    Stmts -> Stmt Stmts
+          ->
+    Stmt  -> E ;
+  `)
+	assert.NoError(t, err)
+	p := packrat.New(grmr).Embed("E", testParser())
+
+	pn := p.Parse(lxr.Lex("1+2*3; 4?5:6;"))
+	if assert.NotNil(t, pn) {
+		assert.Equal(t, "(1+(2*3))", group(pn.Child(0).Child(0)))
+		assert.Equal(t, "(4?5:6)", group(pn.Child(1).Child(0).Child(0)))
+	}
+	assert.Nil(t, p.Parse(lxr.Lex("1+; 2;")))
+}
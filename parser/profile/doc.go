@@ -0,0 +1,22 @@
+// Package profile instruments parsers to find the rules responsible for slow
+// parses.
+//
+// A Profiler is attached to a parser with Instrument:
+//
+//	prof := profile.New()
+//	p := packrat.New(grmr).Instrument(prof)
+//	p.Parse(lexemes)
+//	fmt.Println(prof.Report())
+//
+// For each non-terminal the Report has the number of attempts, memo hits and
+// misses, the time spent and the attempts and accepts for each production,
+// along with the largest memo table seen. When Labels is set, the goroutine is
+// labeled with the current non-terminal so a CPU profile taken with pprof can
+// be broken down by non-terminal using the "parlex.nonterminal" tag. If the
+// goroutine has labels of it's own, set Context to the context holding them so
+// they are kept while parsing and restored after.
+//
+// Parsers call Start at the beginning of a parse and use the Recorder it
+// returns; all the methods of a nil Recorder are no-ops so an uninstrumented
+// parser pays almost nothing.
+package profile
@@ -0,0 +1,292 @@
+package profile
+
+import (
+	"context"
+	"fmt"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LabelKey is the pprof label set to the current non-terminal when Labels is
+// true.
+const LabelKey = "parlex.nonterminal"
+
+// Profiler collects statistics from instrumented parsers. It is safe to share a
+// Profiler between parsers and goroutines. If Labels is true, the goroutine is
+// labeled with the non-terminal being processed so CPU profiles can be broken
+// down by non-terminal. The label is added to the labels of Context, which are
+// restored when the parse is done; if the goroutine already has labels, Context
+// should hold them or they will be lost. A nil Context is treated as
+// context.Background.
+type Profiler struct {
+	Labels  bool
+	Context context.Context
+
+	mux     sync.Mutex
+	nts     map[string]*NonTerminal
+	parses  int
+	time    time.Duration
+	maxMemo int
+}
+
+// New returns a Profiler.
+func New() *Profiler {
+	return &Profiler{
+		nts: make(map[string]*NonTerminal),
+	}
+}
+
+// NonTerminal holds the statistics for one non-terminal. Time is the time spent
+// processing the non-terminal, excluding the time spent in other non-terminals.
+type NonTerminal struct {
+	Name        string
+	Attempts    int
+	MemoHits    int
+	MemoMisses  int
+	Time        time.Duration
+	Productions []Production
+}
+
+// Production holds the number of times a production was attempted and the
+// number of times it was accepted.
+type Production struct {
+	Attempts int
+	Accepts  int
+}
+
+func (nt *NonTerminal) prod(idx int) *Production {
+	if idx >= len(nt.Productions) {
+		nt.Productions = append(nt.Productions, make([]Production, idx+1-len(nt.Productions))...)
+	}
+	return &nt.Productions[idx]
+}
+
+func (nt *NonTerminal) add(nt2 *NonTerminal) {
+	nt.Attempts += nt2.Attempts
+	nt.MemoHits += nt2.MemoHits
+	nt.MemoMisses += nt2.MemoMisses
+	nt.Time += nt2.Time
+	for i, p := range nt2.Productions {
+		pr := nt.prod(i)
+		pr.Attempts += p.Attempts
+		pr.Accepts += p.Accepts
+	}
+}
+
+// Start is called by a parser at the start of a parse. The Recorder collects
+// the statistics for that parse. If the Profiler is nil, Start returns nil and
+// all the methods on a nil Recorder do nothing.
+func (p *Profiler) Start() *Recorder {
+	if p == nil {
+		return nil
+	}
+	r := &Recorder{
+		p:     p,
+		start: time.Now(),
+		nts:   make(map[string]*NonTerminal),
+	}
+	if p.Labels {
+		r.ctx = p.Context
+		if r.ctx == nil {
+			r.ctx = context.Background()
+		}
+	}
+	return r
+}
+
+// Reset clears all the statistics.
+func (p *Profiler) Reset() {
+	p.mux.Lock()
+	p.nts = make(map[string]*NonTerminal)
+	p.parses, p.time, p.maxMemo = 0, 0, 0
+	p.mux.Unlock()
+}
+
+// Report returns the statistics collected so far.
+func (p *Profiler) Report() *Report {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	r := &Report{
+		Parses:       p.parses,
+		Time:         p.time,
+		MaxMemo:      p.maxMemo,
+		NonTerminals: make([]NonTerminal, 0, len(p.nts)),
+	}
+	for _, nt := range p.nts {
+		cp := *nt
+		cp.Productions = append([]Production(nil), nt.Productions...)
+		r.NonTerminals = append(r.NonTerminals, cp)
+	}
+	sort.Slice(r.NonTerminals, func(i, j int) bool {
+		a, b := r.NonTerminals[i], r.NonTerminals[j]
+		if a.Time != b.Time {
+			return a.Time > b.Time
+		}
+		return a.Name < b.Name
+	})
+	return r
+}
+
+// Report of the statistics from a Profiler. The NonTerminals are sorted by Time
+// with the most expensive first.
+type Report struct {
+	Parses       int
+	Time         time.Duration
+	MaxMemo      int
+	NonTerminals []NonTerminal
+}
+
+// NonTerminal returns the statistics for a non-terminal by name.
+func (r *Report) NonTerminal(name string) (NonTerminal, bool) {
+	for _, nt := range r.NonTerminals {
+		if nt.Name == name {
+			return nt, true
+		}
+	}
+	return NonTerminal{}, false
+}
+
+func (r *Report) String() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "parses: %d  time: %s  max memo: %d\n", r.Parses, r.Time, r.MaxMemo)
+	fmt.Fprintf(&buf, "%-20s %12s %10s %10s %10s  %s\n", "non-terminal", "time", "attempts", "hits", "misses", "productions (accepts/attempts)")
+	for _, nt := range r.NonTerminals {
+		prods := make([]string, len(nt.Productions))
+		for i, p := range nt.Productions {
+			prods[i] = fmt.Sprintf("%d:%d/%d", i, p.Accepts, p.Attempts)
+		}
+		fmt.Fprintf(&buf, "%-20s %12s %10d %10d %10d  %s\n", nt.Name, nt.Time, nt.Attempts, nt.MemoHits, nt.MemoMisses, strings.Join(prods, " "))
+	}
+	return buf.String()
+}
+
+// Recorder collects the statistics for a single parse. It is not safe for
+// concurrent use. The statistics are added to the Profiler when Done is called.
+type Recorder struct {
+	p       *Profiler
+	start   time.Time
+	nts     map[string]*NonTerminal
+	stack   []frame
+	ctx     context.Context
+	maxMemo int
+}
+
+type frame struct {
+	nt    *NonTerminal
+	start time.Time
+	child time.Duration
+	ctx   context.Context
+}
+
+func (r *Recorder) get(name string) *NonTerminal {
+	nt, ok := r.nts[name]
+	if !ok {
+		nt = &NonTerminal{Name: name}
+		r.nts[name] = nt
+	}
+	return nt
+}
+
+// Enter is called when the parser starts processing a non-terminal. Every call
+// to Enter must be matched by a call to Exit.
+func (r *Recorder) Enter(name string) {
+	if r == nil {
+		return
+	}
+	f := frame{
+		nt:    r.get(name),
+		start: time.Now(),
+	}
+	if r.ctx != nil {
+		f.ctx = pprof.WithLabels(r.ctx, pprof.Labels(LabelKey, name))
+		pprof.SetGoroutineLabels(f.ctx)
+	}
+	r.stack = append(r.stack, f)
+}
+
+// Exit is called when the parser is done processing the non-terminal from the
+// last call to Enter. The last Exit restores the labels of the Profiler's
+// Context.
+func (r *Recorder) Exit() {
+	if r == nil || len(r.stack) == 0 {
+		return
+	}
+	ln := len(r.stack) - 1
+	f := r.stack[ln]
+	r.stack = r.stack[:ln]
+	d := time.Since(f.start)
+	f.nt.Time += d - f.child
+	ctx := r.ctx
+	if ln > 0 {
+		r.stack[ln-1].child += d
+		ctx = r.stack[ln-1].ctx
+	}
+	if ctx != nil {
+		pprof.SetGoroutineLabels(ctx)
+	}
+}
+
+// Attempt records an attempt to parse a production of a non-terminal.
+func (r *Recorder) Attempt(name string, prod int) {
+	if r == nil {
+		return
+	}
+	nt := r.get(name)
+	nt.Attempts++
+	nt.prod(prod).Attempts++
+}
+
+// Accept records that a production of a non-terminal was accepted.
+func (r *Recorder) Accept(name string, prod int) {
+	if r == nil {
+		return
+	}
+	r.get(name).prod(prod).Accepts++
+}
+
+// Memo records a memo lookup for a non-terminal.
+func (r *Recorder) Memo(name string, hit bool) {
+	if r == nil {
+		return
+	}
+	nt := r.get(name)
+	if hit {
+		nt.MemoHits++
+	} else {
+		nt.MemoMisses++
+	}
+}
+
+// MemoSize records the size of the memo table.
+func (r *Recorder) MemoSize(size int) {
+	if r != nil && size > r.maxMemo {
+		r.maxMemo = size
+	}
+}
+
+// Done adds the statistics from the parse to the Profiler.
+func (r *Recorder) Done() {
+	if r == nil {
+		return
+	}
+	d := time.Since(r.start)
+	p := r.p
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.parses++
+	p.time += d
+	if r.maxMemo > p.maxMemo {
+		p.maxMemo = r.maxMemo
+	}
+	for name, nt := range r.nts {
+		pnt, ok := p.nts[name]
+		if !ok {
+			pnt = &NonTerminal{Name: name}
+			p.nts[name] = pnt
+		}
+		pnt.add(nt)
+	}
+}
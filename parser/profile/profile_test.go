@@ -0,0 +1,80 @@
+package profile
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	var nilProf *Profiler
+	r := nilProf.Start()
+	assert.Nil(t, r)
+	// all no-ops
+	r.Enter("E")
+	r.Attempt("E", 0)
+	r.Exit()
+	r.Done()
+
+	p := New()
+	p.Labels = true
+	for i := 0; i < 2; i++ {
+		r = p.Start()
+		r.Memo("E", false)
+		r.Enter("E")
+		r.Attempt("E", 0)
+		r.Attempt("E", 1)
+		r.Memo("T", false)
+		r.Enter("T")
+		r.Attempt("T", 0)
+		r.Accept("T", 0)
+		r.Exit()
+		r.Accept("E", 1)
+		r.Exit()
+		r.Memo("E", true)
+		r.MemoSize(10 * (i + 1))
+		r.Done()
+	}
+
+	rep := p.Report()
+	assert.Equal(t, 2, rep.Parses)
+	assert.Equal(t, 20, rep.MaxMemo)
+	assert.Len(t, rep.NonTerminals, 2)
+
+	e, ok := rep.NonTerminal("E")
+	assert.True(t, ok)
+	assert.Equal(t, 4, e.Attempts)
+	assert.Equal(t, 2, e.MemoHits)
+	assert.Equal(t, 2, e.MemoMisses)
+	assert.Equal(t, []Production{{2, 0}, {2, 2}}, e.Productions)
+
+	tnt, ok := rep.NonTerminal("T")
+	assert.True(t, ok)
+	assert.Equal(t, []Production{{2, 2}}, tnt.Productions)
+	assert.Contains(t, rep.String(), "0:2/2")
+
+	_, ok = rep.NonTerminal("X")
+	assert.False(t, ok)
+
+	p.Reset()
+	assert.Equal(t, 0, p.Report().Parses)
+}
+
+func TestContextLabels(t *testing.T) {
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("caller", "test"))
+	p := New()
+	p.Labels = true
+	p.Context = ctx
+
+	r := p.Start()
+	r.Enter("E")
+	caller, _ := pprof.Label(r.stack[0].ctx, "caller")
+	assert.Equal(t, "test", caller)
+	nt, _ := pprof.Label(r.stack[0].ctx, LabelKey)
+	assert.Equal(t, "E", nt)
+	r.Exit()
+	r.Done()
+	assert.Equal(t, ctx, r.ctx)
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"github.com/adamcolton/parlex"
 	"github.com/adamcolton/parlex/lexeme"
+	"github.com/adamcolton/parlex/parser/action"
+	"github.com/adamcolton/parlex/parser/profile"
 	"github.com/adamcolton/parlex/symbol/setsymbol"
 	"github.com/adamcolton/parlex/tree"
 )
@@ -12,6 +14,7 @@ import (
 // Topdown is a Top Down parser
 type Topdown struct {
 	parlex.Grammar
+	profiler *profile.Profiler
 }
 
 // ErrLeftRecursion is thrown if the grammar is left recursive. Top down parsing
@@ -28,8 +31,35 @@ func New(grmr parlex.Grammar) (*Topdown, error) {
 	}, nil
 }
 
+// Instrument sets the Profiler that will record statistics for each parse. A
+// nil Profiler turns off profiling.
+func (t *Topdown) Instrument(profiler *profile.Profiler) *Topdown {
+	t.profiler = profiler
+	return t
+}
+
 // Parse implements parlex.Parser
 func (t *Topdown) Parse(lexemes []parlex.Lexeme) parlex.ParseNode {
+	node := t.parse(lexemes, nil)
+	if node == nil {
+		return nil
+	}
+	return node
+}
+
+// ParseActions parses the lexemes and then runs the actions bottom up over the
+// accepted derivation. It returns the value from the action of the root along
+// with the parse tree. If the parse fails, both will be nil.
+func (t *Topdown) ParseActions(lexemes []parlex.Lexeme, actions *action.Actions) (interface{}, parlex.ParseNode) {
+	d := make(action.Derivation)
+	node := t.parse(lexemes, d)
+	if node == nil {
+		return nil, nil
+	}
+	return actions.Eval(node, d.Production), node
+}
+
+func (t *Topdown) parse(lexemes []parlex.Lexeme, d action.Derivation) *tree.PN {
 	nts := t.NonTerminals()
 	if len(nts) == 0 {
 		return nil
@@ -41,12 +71,13 @@ func (t *Topdown) Parse(lexemes []parlex.Lexeme) parlex.ParseNode {
 		lxs:     set.LoadLexemes(lexemes),
 		memo:    make(map[treeKey]*acceptResp),
 		set:     set,
+		d:       d,
+		rec:     t.profiler.Start(),
 	}
+	defer op.rec.Done()
 	start := op.set.Symbol(nts[0]).Idx()
 	node := op.accept(treeKey{start, 0}, true).node()
-	if node == nil {
-		return nil
-	}
+	op.rec.MemoSize(len(op.memo))
 	return node
 }
 
@@ -83,13 +114,21 @@ type tdOp struct {
 	lxs  []*lexeme.Lexeme
 	memo map[treeKey]*acceptResp
 	set  *setsymbol.Set
+	d    action.Derivation
+	rec  *profile.Recorder
 }
 
 func (op *tdOp) accept(key treeKey, all bool) *acceptResp {
-	if resp, ok := op.memo[key]; ok {
+	resp, ok := op.memo[key]
+	if op.rec != nil {
+		if symbol := op.set.ByIdx(key.idx); op.Productions(symbol) != nil {
+			op.rec.Memo(symbol.String(), ok)
+		}
+	}
+	if ok {
 		return resp
 	}
-	resp := op.tryAccept(key, all)
+	resp = op.tryAccept(key, all)
 	op.memo[key] = resp
 	return resp
 }
@@ -109,9 +148,20 @@ func (op *tdOp) tryAccept(key treeKey, all bool) *acceptResp {
 		return nil
 	}
 
+	var name string
+	if op.rec != nil {
+		name = symbol.String()
+		op.rec.Enter(name)
+		defer op.rec.Exit()
+	}
 	for i := productions.Iter(); i.Next(); {
+		op.rec.Attempt(name, i.Idx)
 		accepts := op.acceptProd(key, i.Production)
 		if accepts != nil && (!all || accepts.end == len(op.lxs)) {
+			op.rec.Accept(name, i.Idx)
+			if op.d != nil {
+				op.d[accepts.PN] = i.Idx
+			}
 			return accepts
 		}
 	}
@@ -132,6 +182,9 @@ func (op *tdOp) acceptProd(key treeKey, prod parlex.Production) *acceptResp {
 		children[i.Idx], pos = resp.PN, resp.end
 	}
 
-	symbol := op.set.ByIdx(key.idx)
-	return resp(lexeme.New(symbol), pos, children...)
+	lx := lexeme.New(op.set.ByIdx(key.idx))
+	for _, c := range children {
+		lx.Span(c.Lexeme)
+	}
+	return resp(lx, pos, children...)
 }
@@ -1,10 +1,14 @@
 package topdown
 
 import (
+	"github.com/adamcolton/parlex"
 	"github.com/adamcolton/parlex/grammar"
 	"github.com/adamcolton/parlex/lexer/simplelexer"
+	"github.com/adamcolton/parlex/parser/action"
+	"github.com/adamcolton/parlex/parser/profile"
 	"github.com/adamcolton/parlex/tree"
 	"github.com/stretchr/testify/assert"
+	"strconv"
 	"testing"
 )
 
@@ -112,3 +116,96 @@ func TestNil(t *testing.T) {
 	pn := p.Parse(lxs)
 	assert.NotNil(t, pn)
 }
+
+func TestActions(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    ( /\(/
+    ) /\)/
+    op /[+\-\*\/]/
+    int /\d+/
+    space /\s+/ -
+  `)
+	assert.NoError(t, err)
+	grmr, err := grammar.New(`
+    E -> T op E
+      -> T
+    T -> ( E )
+      -> int
+  `)
+	assert.NoError(t, err)
+
+	value := func(arg interface{}) int {
+		i, _ := strconv.Atoi(arg.(parlex.Lexeme).Value())
+		return i
+	}
+	actions := action.New().
+		MustAddRule(grmr, "E -> T op E", func(node parlex.ParseNode, args []interface{}) interface{} {
+			a, b := args[0].(int), args[2].(int)
+			switch args[1].(parlex.Lexeme).Value() {
+			case "+":
+				return a + b
+			case "-":
+				return a - b
+			case "/":
+				return a / b
+			}
+			return a * b
+		}).
+		MustAddRule(grmr, "T -> ( E )", func(node parlex.ParseNode, args []interface{}) interface{} {
+			return args[1]
+		}).
+		MustAddRule(grmr, "T -> int", func(node parlex.ParseNode, args []interface{}) interface{} {
+			return value(args[0])
+		})
+
+	p, err := New(grmr)
+	assert.NoError(t, err)
+	v, pn := p.ParseActions(lxr.Lex("2*(1+2)"), actions)
+	assert.Equal(t, 6, v)
+	assert.NotNil(t, pn)
+
+	v, pn = p.ParseActions(lxr.Lex("5*("), actions)
+	assert.Nil(t, v)
+	assert.Nil(t, pn)
+}
+
+func TestInstrument(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    ( /\(/
+    ) /\)/
+    op /[+\-\*\/]/
+    int /\d+/
+    space /\s+/ -
+  `)
+	assert.NoError(t, err)
+	grmr, err := grammar.New(`
+    E -> T op E
+      -> T
+    T -> ( E )
+      -> int
+  `)
+	assert.NoError(t, err)
+
+	prof := profile.New()
+	prof.Labels = true
+	p, err := New(grmr)
+	assert.NoError(t, err)
+	p.Instrument(prof)
+	assert.NotNil(t, p.Parse(lxr.Lex("1+(2+3)")))
+	assert.NotNil(t, p.Parse(lxr.Lex("4")))
+
+	rep := prof.Report()
+	assert.Equal(t, 2, rep.Parses)
+	assert.True(t, rep.MaxMemo > 0)
+	e, ok := rep.NonTerminal("E")
+	if assert.True(t, ok) {
+		assert.True(t, e.Attempts > 0)
+		assert.True(t, e.Productions[0].Accepts > 0)
+	}
+	tnt, ok := rep.NonTerminal("T")
+	if assert.True(t, ok) {
+		assert.True(t, tnt.Productions[0].Accepts > 0)
+		assert.True(t, tnt.Productions[1].Accepts > 0)
+		assert.True(t, tnt.MemoMisses > 0)
+	}
+}
@@ -0,0 +1,20 @@
+// Package pretty provides a document algebra in the style of Wadler's "A
+// prettier printer" and a way to format a parse tree with it.
+//
+// A Doc is built from Text, Line, SoftLine, HardLine, Concat, Nest and Group.
+// When rendered, each Group is printed flat, with lines as spaces, if it fits
+// in the remaining width; otherwise it's lines become newlines.
+//
+// A Spec maps non-terminals to Docs. The Docs in a Spec can refer to the node
+// being formatted with Value, Child and Children, so a formatter for a parlex
+// language can be declared in a few lines
+//   spec := pretty.Spec{
+//     "Array": pretty.Group(
+//       pretty.Text("["),
+//       pretty.Nest(2, pretty.SoftLine, pretty.Children(pretty.Text(","), pretty.Line)),
+//       pretty.SoftLine,
+//       pretty.Text("]"),
+//     ),
+//   }
+//   str := spec.Format(root, 80)
+package pretty
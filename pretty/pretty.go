@@ -0,0 +1,298 @@
+package pretty
+
+import (
+	"strings"
+
+	"github.com/adamcolton/parlex"
+)
+
+// Doc is a document that can be rendered to a target width. Docs are
+// immutable and can be shared.
+type Doc interface {
+	isDoc()
+}
+
+type text string
+
+type line struct {
+	flat string
+	hard bool
+}
+
+type concat []Doc
+
+type nest struct {
+	indent int
+	doc    Doc
+}
+
+type group struct {
+	doc Doc
+}
+
+func (text) isDoc()   {}
+func (line) isDoc()   {}
+func (concat) isDoc() {}
+func (nest) isDoc()   {}
+func (group) isDoc()  {}
+
+// Text is a literal string. It should not contain newlines, use HardLine
+// instead.
+func Text(str string) Doc { return text(str) }
+
+var (
+	// Line is a newline, or a space when it's group is flat.
+	Line Doc = line{flat: " "}
+	// SoftLine is a newline, or nothing when it's group is flat.
+	SoftLine Doc = line{}
+	// HardLine is always a newline and forces any group containing it to
+	// break.
+	HardLine Doc = line{hard: true}
+)
+
+// Concat joins docs together.
+func Concat(docs ...Doc) Doc {
+	if len(docs) == 1 {
+		return docs[0]
+	}
+	return concat(docs)
+}
+
+// Nest increases the indentation of any line breaks in docs.
+func Nest(indent int, docs ...Doc) Doc {
+	return nest{
+		indent: indent,
+		doc:    Concat(docs...),
+	}
+}
+
+// Group marks docs as a unit that will be rendered flat if it fits on the
+// remaining line.
+func Group(docs ...Doc) Doc {
+	return group{Concat(docs...)}
+}
+
+// Join places sep between each of the docs.
+func Join(sep Doc, docs []Doc) Doc {
+	if len(docs) == 0 {
+		return concat(nil)
+	}
+	out := make(concat, 0, len(docs)*2-1)
+	for i, d := range docs {
+		if i != 0 {
+			out = append(out, sep)
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+type item struct {
+	indent int
+	flat   bool
+	doc    Doc
+}
+
+// Render a Doc, trying to keep lines within width.
+func Render(d Doc, width int) string {
+	var buf strings.Builder
+	col := 0
+	stack := []item{{doc: d}}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch d := it.doc.(type) {
+		case text:
+			buf.WriteString(string(d))
+			col += len(d)
+		case line:
+			if it.flat && !d.hard {
+				buf.WriteString(d.flat)
+				col += len(d.flat)
+			} else {
+				buf.WriteString("\n")
+				buf.WriteString(strings.Repeat(" ", it.indent))
+				col = it.indent
+			}
+		case concat:
+			for i := len(d) - 1; i >= 0; i-- {
+				stack = append(stack, item{it.indent, it.flat, d[i]})
+			}
+		case nest:
+			stack = append(stack, item{it.indent + d.indent, it.flat, d.doc})
+		case group:
+			flat := it.flat || fits(width-col, append(stack, item{it.indent, true, d.doc}))
+			stack = append(stack, item{it.indent, flat, d.doc})
+		}
+	}
+	return buf.String()
+}
+
+// fits checks if the top of the stack can be rendered in the remaining width
+// before reaching a line break.
+func fits(remaining int, stack []item) bool {
+	stack = append([]item(nil), stack...)
+	for remaining >= 0 {
+		if len(stack) == 0 {
+			return true
+		}
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch d := it.doc.(type) {
+		case text:
+			remaining -= len(d)
+		case line:
+			if !it.flat {
+				return true
+			}
+			if d.hard {
+				return false
+			}
+			remaining -= len(d.flat)
+		case concat:
+			for i := len(d) - 1; i >= 0; i-- {
+				stack = append(stack, item{it.indent, it.flat, d[i]})
+			}
+		case nest:
+			stack = append(stack, item{it.indent + d.indent, it.flat, d.doc})
+		case group:
+			stack = append(stack, item{it.indent, it.flat, d.doc})
+		}
+	}
+	return false
+}
+
+// nodeDoc is a Doc that is resolved against the node being formatted.
+type nodeDoc func(s Spec, node parlex.ParseNode) Doc
+
+func (nodeDoc) isDoc() {}
+
+// Value is replaced by the value of the node being formatted.
+var Value Doc = nodeDoc(func(s Spec, node parlex.ParseNode) Doc {
+	return Text(node.Value())
+})
+
+// Child is replaced by the formatted child at cIdx. If cIdx is negative, it
+// will find the child relative to the end. If there is no child at cIdx, it is
+// replaced with nothing.
+func Child(cIdx int) Doc {
+	return nodeDoc(func(s Spec, node parlex.ParseNode) Doc {
+		i := cIdx
+		if i < 0 {
+			i += node.Children()
+		}
+		if i < 0 || i >= node.Children() {
+			return concat(nil)
+		}
+		return s.Doc(node.Child(i))
+	})
+}
+
+// Children is replaced by all the formatted children, separated by sep.
+func Children(sep ...Doc) Doc {
+	sepDoc := Concat(sep...)
+	return nodeDoc(func(s Spec, node parlex.ParseNode) Doc {
+		docs := make([]Doc, node.Children())
+		for i := range docs {
+			docs[i] = s.Doc(node.Child(i))
+		}
+		return Join(sepDoc, docs)
+	})
+}
+
+// Comments is replaced by the leading trivia of the node being formatted that
+// matches any of the kinds, each followed by a HardLine. This requires a lexer
+// that keeps trivia. Other trivia, like whitespace, is dropped so that the
+// layout still controls spacing.
+func Comments(kinds ...string) Doc {
+	return nodeDoc(func(s Spec, node parlex.ParseNode) Doc {
+		t, ok := node.(parlex.Trivia)
+		if !ok {
+			return concat(nil)
+		}
+		var out concat
+		for _, lead := range t.Leading() {
+			k := lead.Kind().String()
+			for _, kind := range kinds {
+				if k == kind {
+					out = append(out, Text(strings.TrimSpace(lead.Value())), HardLine)
+					break
+				}
+			}
+		}
+		return out
+	})
+}
+
+// Func allows arbitrary logic when building the Doc for a node.
+func Func(fn func(node parlex.ParseNode) Doc) Doc {
+	return nodeDoc(func(s Spec, node parlex.ParseNode) Doc {
+		return s.bind(fn(node), node)
+	})
+}
+
+// If uses then if the condition is true for the node being formatted,
+// otherwise it uses otherwise. Either can be nil.
+func If(condition func(node parlex.ParseNode) bool, then, otherwise Doc) Doc {
+	return nodeDoc(func(s Spec, node parlex.ParseNode) Doc {
+		d := otherwise
+		if condition(node) {
+			d = then
+		}
+		if d == nil {
+			return concat(nil)
+		}
+		return s.bind(d, node)
+	})
+}
+
+// Spec maps the kind of a node to the Doc that will be used to format it.
+type Spec map[string]Doc
+
+// Doc builds the Doc for a node. If there is no entry in the Spec for the
+// node's kind, the node's value is followed by it's children, separated by
+// Lines.
+func (s Spec) Doc(node parlex.ParseNode) Doc {
+	d, ok := s[node.Kind().String()]
+	if !ok {
+		d = defaultDoc(node)
+	}
+	return s.bind(d, node)
+}
+
+func defaultDoc(node parlex.ParseNode) Doc {
+	if node.Children() == 0 {
+		return Value
+	}
+	if node.Value() == "" {
+		return Group(Children(Line))
+	}
+	return Group(Value, Line, Children(Line))
+}
+
+// bind resolves any node dependant docs against node.
+func (s Spec) bind(d Doc, node parlex.ParseNode) Doc {
+	switch d := d.(type) {
+	case nodeDoc:
+		return d(s, node)
+	case concat:
+		out := make(concat, len(d))
+		for i, c := range d {
+			out[i] = s.bind(c, node)
+		}
+		return out
+	case nest:
+		return nest{d.indent, s.bind(d.doc, node)}
+	case group:
+		return group{s.bind(d.doc, node)}
+	}
+	return d
+}
+
+// Format renders a parse tree using the Spec.
+func (s Spec) Format(node parlex.ParseNode, width int) string {
+	if node == nil {
+		return ""
+	}
+	return Render(s.Doc(node), width)
+}
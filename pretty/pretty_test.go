@@ -0,0 +1,142 @@
+package pretty
+
+import (
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/lexeme"
+	"github.com/adamcolton/parlex/lexer/simplelexer"
+	"github.com/adamcolton/parlex/tree"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	d := Group(
+		Text("f("),
+		Nest(2, SoftLine, Join(Concat(Text(","), Line), []Doc{Text("alpha"), Text("beta"), Text("gamma")})),
+		SoftLine,
+		Text(")"),
+	)
+	assert.Equal(t, "f(alpha, beta, gamma)", Render(d, 80))
+	assert.Equal(t, "f(\n  alpha,\n  beta,\n  gamma\n)", Render(d, 10))
+
+	d = Group(Text("a"), HardLine, Text("b"))
+	assert.Equal(t, "a\nb", Render(d, 80))
+}
+
+var jsonSpec = Spec{
+	"Array": Group(
+		Text("["),
+		Nest(2, SoftLine, Children(Text(","), Line)),
+		SoftLine,
+		Text("]"),
+	),
+	"Object": If(func(node parlex.ParseNode) bool { return node.Children() == 0 },
+		Text("{}"),
+		Group(
+			Text("{"),
+			Nest(2, Line, Children(Text(","), Line)),
+			Line,
+			Text("}"),
+		),
+	),
+	"KeyVal": Concat(Value, Text(": "), Child(0)),
+}
+
+func TestFormat(t *testing.T) {
+	root, err := tree.New(`
+    Object {
+      KeyVal: "\"a\"" {
+        number: "1"
+      }
+      KeyVal: "\"list\"" {
+        Array {
+          number: "1"
+          number: "2"
+          number: "3"
+        }
+      }
+      KeyVal: "\"empty\"" {
+        Object
+      }
+    }
+  `)
+	assert.NoError(t, err)
+
+	assert.Equal(t, `{ "a": 1, "list": [1, 2, 3], "empty": {} }`, jsonSpec.Format(root, 80))
+	assert.Equal(t, `{
+  "a": 1,
+  "list": [1, 2, 3],
+  "empty": {}
+}`, jsonSpec.Format(root, 30))
+	assert.Equal(t, `{
+  "a": 1,
+  "list": [
+    1,
+    2,
+    3
+  ],
+  "empty": {}
+}`, jsonSpec.Format(root, 10))
+}
+
+func TestNegativeChild(t *testing.T) {
+	short, err := tree.New(`
+    List {
+      n: "1"
+      n: "2"
+      n: "3"
+    }
+  `)
+	assert.NoError(t, err)
+	long, err := tree.New(`
+    List {
+      n: "1"
+      n: "2"
+      n: "3"
+      n: "4"
+      n: "5"
+    }
+  `)
+	assert.NoError(t, err)
+
+	spec := Spec{
+		"List": Concat(Text("["), Child(-1), Text("]")),
+	}
+	assert.Equal(t, "[3]", spec.Format(short, 80))
+	assert.Equal(t, "[5]", spec.Format(long, 80))
+	assert.Equal(t, "[3]", spec.Format(short, 80))
+}
+
+func TestDefaultDoc(t *testing.T) {
+	root, err := tree.New(`
+    op: "+" {
+      number: "1"
+      number: "2"
+    }
+  `)
+	assert.NoError(t, err)
+	assert.Equal(t, "+ 1 2", Spec{}.Format(root, 80))
+}
+
+func TestComments(t *testing.T) {
+	lxr, err := simplelexer.New(`
+    word    /\w+/
+    comment /\/\/[^\n]*/ -
+    space   /\s+/ -
+  `)
+	assert.NoError(t, err)
+	lxs := lxr.KeepTrivia().Lex("// first\nalpha // not leading\n   beta")
+	root := &tree.PN{
+		Lexeme: lexeme.String("List"),
+	}
+	for _, lx := range lxs {
+		root.C = append(root.C, &tree.PN{Lexeme: lx, P: root})
+	}
+
+	spec := Spec{
+		"List": Children(HardLine),
+		"word": Concat(Comments("comment"), Value),
+	}
+	assert.Equal(t, "// first\nalpha\nbeta", spec.Format(root, 80))
+}
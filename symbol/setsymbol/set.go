@@ -290,11 +290,12 @@ func (s *Set) LoadGrammar(grammar parlex.Grammar) {
 }
 
 // LoadLexemes takes a slice of Lexemes and loads the symbols. This is
-// useful to get the size of the set.
+// useful to get the size of the set. Any trivia on the lexemes is kept.
 func (s *Set) LoadLexemes(lexemes []parlex.Lexeme) []*lexeme.Lexeme {
 	out := make([]*lexeme.Lexeme, len(lexemes))
 	for i, lx := range lexemes {
-		out[i] = lexeme.New(s.Symbol(lx.Kind())).Set(lx.Value()).At(lx.Pos())
+		out[i] = lexeme.Copy(lx)
+		out[i].K = s.Symbol(lx.Kind())
 	}
 	return out
 }
@@ -0,0 +1,278 @@
+package ast
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/adamcolton/parlex"
+)
+
+// Unmarshaler is fulfilled by a type that can populate itself from a node.
+type Unmarshaler interface {
+	UnmarshalParseNode(node parlex.ParseNode, r Registry) error
+}
+
+// Registry maps a kind to the type used to populate an interface field from a
+// node of that kind.
+type Registry map[string]reflect.Type
+
+// Register the type of zero for kind. If zero is a pointer, the registered
+// type is the pointer type and nodes are unmarshaled into a newly allocated
+// value.
+func (r Registry) Register(kind string, zero interface{}) {
+	r[kind] = reflect.TypeOf(zero)
+}
+
+// Error is returned when a node cannot be unmarshaled into a value.
+type Error struct {
+	Line, Col int
+	Kind      string
+	Value     string
+	Err       error
+}
+
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s %d:%d) %q: %s", e.Kind, e.Line, e.Col, e.Value, e.Err.Error())
+	}
+	return fmt.Sprintf("%s) %q: %s", e.Kind, e.Value, e.Err.Error())
+}
+
+// Standard errors
+var (
+	ErrNotPointer   = errors.New("Unmarshal requires a non-nil pointer")
+	ErrMissingChild = errors.New("Missing child")
+	ErrUnregistered = errors.New("Kind is not registered")
+	ErrBadTag       = errors.New("Bad parlex tag")
+)
+
+// Unmarshal populates v, which must be a pointer, from node without a
+// registry.
+func Unmarshal(node parlex.ParseNode, v interface{}) error {
+	return Registry(nil).Unmarshal(node, v)
+}
+
+// Unmarshal populates v, which must be a pointer, from node. Interface values
+// are resolved using the registry.
+func (r Registry) Unmarshal(node parlex.ParseNode, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return ErrNotPointer
+	}
+	return r.value(node, rv.Elem())
+}
+
+var unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+func newErr(node parlex.ParseNode, err error) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	l, c := node.Pos()
+	return &Error{
+		Line:  l,
+		Col:   c,
+		Kind:  node.Kind().String(),
+		Value: node.Value(),
+		Err:   err,
+	}
+}
+
+func (r Registry) value(node parlex.ParseNode, v reflect.Value) error {
+	if v.CanAddr() && v.Addr().Type().Implements(unmarshalerType) {
+		if err := v.Addr().Interface().(Unmarshaler).UnmarshalParseNode(node, r); err != nil {
+			return newErr(node, err)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return r.value(node, v.Elem())
+	case reflect.Interface:
+		return r.iface(node, v)
+	case reflect.Struct:
+		return r.strct(node, v)
+	case reflect.Slice:
+		return r.slice(children(node, ""), v)
+	}
+	if err := setScalar(node.Value(), v); err != nil {
+		return newErr(node, err)
+	}
+	return nil
+}
+
+func (r Registry) iface(node parlex.ParseNode, v reflect.Value) error {
+	t, ok := r[node.Kind().String()]
+	if !ok {
+		return newErr(node, ErrUnregistered)
+	}
+	if !t.AssignableTo(v.Type()) {
+		return newErr(node, fmt.Errorf("%s does not implement %s", t, v.Type()))
+	}
+	nv := reflect.New(t).Elem()
+	if err := r.value(node, nv); err != nil {
+		return err
+	}
+	v.Set(nv)
+	return nil
+}
+
+func (r Registry) slice(nodes []parlex.ParseNode, v reflect.Value) error {
+	s := reflect.MakeSlice(v.Type(), len(nodes), len(nodes))
+	for i, n := range nodes {
+		if err := r.value(n, s.Index(i)); err != nil {
+			return err
+		}
+	}
+	v.Set(s)
+	return nil
+}
+
+func (r Registry) strct(node parlex.ParseNode, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		str, ok := f.Tag.Lookup("parlex")
+		if !ok || f.PkgPath != "" {
+			continue
+		}
+		tg, err := parseTag(str)
+		if err != nil {
+			return newErr(node, fmt.Errorf("%s: %s", f.Name, err.Error()))
+		}
+		if err := r.field(node, v.Field(i), tg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r Registry) field(node parlex.ParseNode, v reflect.Value, tg tag) error {
+	switch {
+	case tg.value:
+		if err := setScalar(node.Value(), v); err != nil {
+			return newErr(node, err)
+		}
+		return nil
+	case tg.kind:
+		if err := setScalar(node.Kind().String(), v); err != nil {
+			return newErr(node, err)
+		}
+		return nil
+	}
+
+	cs := children(node, tg.ofKind)
+	if tg.children || (!tg.hasChild && tg.ofKind != "" && v.Kind() == reflect.Slice) {
+		if v.Kind() != reflect.Slice {
+			return newErr(node, ErrBadTag)
+		}
+		return r.slice(cs, v)
+	}
+
+	idx := tg.child
+	if idx < 0 {
+		idx += len(cs)
+	}
+	if idx < 0 || idx >= len(cs) {
+		if tg.optional {
+			return nil
+		}
+		return newErr(node, ErrMissingChild)
+	}
+	return r.value(cs[idx], v)
+}
+
+func children(node parlex.ParseNode, kind string) []parlex.ParseNode {
+	var out []parlex.ParseNode
+	for i := 0; i < node.Children(); i++ {
+		c := node.Child(i)
+		if kind == "" || c.Kind().String() == kind {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func setScalar(str string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(str)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(str, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(str, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(str, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setScalar(str, v.Elem())
+	default:
+		return fmt.Errorf("Cannot convert value to %s", v.Type())
+	}
+	return nil
+}
+
+type tag struct {
+	value, kind, children, optional bool
+	hasChild                        bool
+	child                           int
+	ofKind                          string
+}
+
+func parseTag(str string) (tag, error) {
+	var tg tag
+	for _, opt := range strings.Split(str, ",") {
+		opt = strings.TrimSpace(opt)
+		kv := strings.SplitN(opt, "=", 2)
+		switch {
+		case opt == "value":
+			tg.value = true
+		case opt == "kind":
+			tg.kind = true
+		case opt == "children":
+			tg.children = true
+		case opt == "optional":
+			tg.optional = true
+		case len(kv) == 2 && kv[0] == "kind":
+			tg.ofKind = kv[1]
+		case len(kv) == 2 && kv[0] == "child":
+			i, err := strconv.Atoi(kv[1])
+			if err != nil {
+				return tg, ErrBadTag
+			}
+			tg.child = i
+			tg.hasChild = true
+		case opt == "":
+		default:
+			return tg, ErrBadTag
+		}
+	}
+	return tg, nil
+}
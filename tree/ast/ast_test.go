@@ -0,0 +1,178 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/lexeme"
+	"github.com/adamcolton/parlex/tree"
+	"github.com/stretchr/testify/assert"
+)
+
+type Expr interface {
+	Eval() float64
+}
+
+type Number float64
+
+func (n Number) Eval() float64 { return float64(n) }
+
+type BinOp struct {
+	Op string `parlex:"value"`
+	A  Expr   `parlex:"child=0"`
+	B  Expr   `parlex:"child=-1"`
+}
+
+func (o *BinOp) Eval() float64 {
+	a, b := o.A.Eval(), o.B.Eval()
+	switch o.Op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	}
+	return a / b
+}
+
+func TestSumType(t *testing.T) {
+	root, err := tree.New(`
+    op: "*" {
+      op: "+" {
+        number: "1"
+        number: "2"
+      }
+      number: "4.5"
+    }
+  `)
+	assert.NoError(t, err)
+
+	reg := Registry{}
+	reg.Register("op", &BinOp{})
+	reg.Register("number", Number(0))
+
+	var e Expr
+	assert.NoError(t, reg.Unmarshal(root, &e))
+	assert.Equal(t, 13.5, e.Eval())
+
+	err = Unmarshal(root, &e)
+	assert.Equal(t, ErrUnregistered, err.(*Error).Err)
+}
+
+type Pair struct {
+	Key  string `parlex:"value"`
+	Kind string `parlex:"kind"`
+	Val  *Value `parlex:"child=0"`
+}
+
+type Value struct {
+	Str  string  `parlex:"value"`
+	Nums []int   `parlex:"kind=number"`
+	All  []Value `parlex:"children"`
+	Last *int    `parlex:"kind=number,child=-1,optional"`
+}
+
+type Object struct {
+	Pairs  []Pair `parlex:"kind=Pair"`
+	First  Pair   `parlex:"kind=Pair"`
+	Second string `parlex:"kind=Pair,child=1"`
+	Extra  string `parlex:"child=10,optional"`
+	Ignore string
+}
+
+func TestStruct(t *testing.T) {
+	root, err := tree.New(`
+    Object {
+      Pair: "a" {
+        string: "x"
+      }
+      Other
+      Pair: "b" {
+        Array {
+          number: "1"
+          string: "y"
+          number: "0x10"
+        }
+      }
+    }
+  `)
+	assert.NoError(t, err)
+
+	obj := Object{Extra: "unchanged", Ignore: "unchanged"}
+	assert.NoError(t, Unmarshal(root, &obj))
+	assert.Len(t, obj.Pairs, 2)
+	assert.Equal(t, "a", obj.First.Key)
+	assert.Equal(t, "Pair", obj.First.Kind)
+	assert.Equal(t, "x", obj.First.Val.Str)
+	assert.Nil(t, obj.First.Val.Last)
+	assert.Equal(t, "b", obj.Second)
+	assert.Equal(t, "unchanged", obj.Extra)
+	assert.Equal(t, "unchanged", obj.Ignore)
+
+	arr := obj.Pairs[1].Val
+	assert.Equal(t, []int{1, 16}, arr.Nums)
+	assert.Equal(t, 16, *arr.Last)
+	if assert.Len(t, arr.All, 3) {
+		assert.Equal(t, "y", arr.All[1].Str)
+	}
+}
+
+type Strict struct {
+	Val  int `parlex:"child=0"`
+	Next int `parlex:"child=1"`
+}
+
+func TestErrors(t *testing.T) {
+	root := &tree.PN{
+		Lexeme: lexeme.String("List"),
+	}
+	root.C = []*tree.PN{
+		{
+			Lexeme: &lexeme.Lexeme{K: lexeme.String("number").K, V: "abc", L: 3, C: 5},
+			P:      root,
+		},
+	}
+
+	var s Strict
+	err := Unmarshal(root, &s)
+	if assert.IsType(t, &Error{}, err) {
+		e := err.(*Error)
+		assert.Equal(t, 3, e.Line)
+		assert.Equal(t, 5, e.Col)
+		assert.Equal(t, "number", e.Kind)
+		assert.Equal(t, `number 3:5) "abc": strconv.ParseInt: parsing "abc": invalid syntax`, e.Error())
+	}
+
+	root.C[0].Lexeme = lexeme.String("number").Set("12")
+	err = Unmarshal(root, &s)
+	assert.Equal(t, ErrMissingChild, err.(*Error).Err)
+	assert.Equal(t, 12, s.Val)
+
+	assert.Equal(t, ErrNotPointer, Unmarshal(root, s))
+
+	var bad struct {
+		X int `parlex:"foo"`
+	}
+	assert.Error(t, Unmarshal(root, &bad))
+}
+
+type Upper string
+
+func (u *Upper) UnmarshalParseNode(node parlex.ParseNode, r Registry) error {
+	*u = Upper(node.Kind().String() + ":" + node.Value())
+	return nil
+}
+
+func TestUnmarshaler(t *testing.T) {
+	root, err := tree.New(`
+    List {
+      word: "a"
+      word: "b"
+    }
+  `)
+	assert.NoError(t, err)
+	var us []Upper
+	assert.NoError(t, Unmarshal(root, &us))
+	assert.Equal(t, []Upper{"word:a", "word:b"}, us)
+}
@@ -0,0 +1,43 @@
+// Package ast unmarshals a reduced parse tree into Go types using reflection
+// and struct tags, replacing the hand written tree walk that usually follows a
+// reduction.
+//
+// Only fields with a parlex tag are populated. The tag is a comma separated
+// list of options:
+//
+//	value      the field is set from the node's value
+//	kind       the field is set to the node's kind
+//	child=N    the field is set from the child at index N, negative indexes are
+//	           relative to the end
+//	kind=X     only children of kind X are considered; with child=N it selects
+//	           the Nth child of that kind
+//	children   the field, which must be a slice, is set from every child, or
+//	           every child of kind X
+//	optional   a missing child leaves the field unchanged instead of returning
+//	           an error
+//
+// A slice field tagged with kind=X and no child index is also filled from every
+// child of kind X.
+//
+// The value of a node populates a string, bool, int, uint or float by
+// conversion; a struct is populated from it's tags; a pointer is allocated and
+// then populated. An interface field is populated by looking up the node's kind
+// in a Registry, which allows a sum type to be represented by an interface and
+// a set of struct types. Any type implementing Unmarshaler populates itself.
+//
+// Conversion failures are returned as an *Error that carries the position of
+// the node that could not be converted.
+//
+//	type Pair struct {
+//	  Key string `parlex:"value"`
+//	  Val Value  `parlex:"child=0"`
+//	}
+//	type Object struct {
+//	  Pairs []Pair `parlex:"kind=Pair"`
+//	}
+//	reg := ast.Registry{}
+//	reg.Register("Object", &Object{})
+//	reg.Register("number", Number(0))
+//	var obj Object
+//	err := reg.Unmarshal(root, &obj)
+package ast
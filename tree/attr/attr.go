@@ -0,0 +1,216 @@
+package attr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/adamcolton/parlex"
+)
+
+// Standard errors
+var (
+	ErrCycle      = errors.New("Circular attribute dependency")
+	ErrNoEquation = errors.New("No equation for attribute")
+)
+
+// Error is returned when an attribute cannot be evaluated.
+type Error struct {
+	Attr      string
+	Kind      string
+	Line, Col int
+	Err       error
+}
+
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s %d:%d) %s: %s", e.Kind, e.Line, e.Col, e.Attr, e.Err.Error())
+	}
+	return fmt.Sprintf("%s) %s: %s", e.Kind, e.Attr, e.Err.Error())
+}
+
+// Synthesized computes an attribute of a node, generally from the attributes
+// of it's children.
+type Synthesized func(node *Node) interface{}
+
+// Inherited computes an attribute of the child at cIdx from the parent and
+// it's other children.
+type Inherited func(parent *Node, cIdx int) interface{}
+
+type key struct {
+	attr, on string
+}
+
+// Grammar holds the attribute equations. Equations are declared either on a
+// kind, "E", or on a production, "E -> E op E", which matches a node of kind E
+// whose children are of kind E, op and E. An equation on a production takes
+// precedence over one on a kind.
+type Grammar struct {
+	syn  map[key]Synthesized
+	inh  map[key]Inherited
+	root map[string]interface{}
+}
+
+// New returns an empty Grammar.
+func New() *Grammar {
+	return &Grammar{
+		syn:  make(map[key]Synthesized),
+		inh:  make(map[key]Inherited),
+		root: make(map[string]interface{}),
+	}
+}
+
+// Syn declares a synthesized attribute for a kind or production.
+func (g *Grammar) Syn(attr, on string, fn Synthesized) *Grammar {
+	g.syn[key{attr, normalize(on)}] = fn
+	return g
+}
+
+// Inh declares an inherited attribute that a node of the given kind or
+// production passes to it's children. If a node has no equation for an
+// inherited attribute of it's child, the child receives the parent's value.
+func (g *Grammar) Inh(attr, on string, fn Inherited) *Grammar {
+	g.inh[key{attr, normalize(on)}] = fn
+	return g
+}
+
+// Root sets the value of an inherited attribute at the root of the tree.
+func (g *Grammar) Root(attr string, val interface{}) *Grammar {
+	g.root[attr] = val
+	return g
+}
+
+func normalize(on string) string {
+	return strings.Join(strings.Fields(on), " ")
+}
+
+// production returns the production string for a node.
+func production(node parlex.ParseNode) string {
+	s := make([]string, 0, node.Children()+2)
+	s = append(s, node.Kind().String(), "->")
+	for i := 0; i < node.Children(); i++ {
+		s = append(s, node.Child(i).Kind().String())
+	}
+	return strings.Join(s, " ")
+}
+
+func (g *Grammar) synFor(attr string, node parlex.ParseNode) Synthesized {
+	if fn, ok := g.syn[key{attr, production(node)}]; ok {
+		return fn
+	}
+	return g.syn[key{attr, node.Kind().String()}]
+}
+
+func (g *Grammar) inhFor(attr string, node parlex.ParseNode) Inherited {
+	if fn, ok := g.inh[key{attr, production(node)}]; ok {
+		return fn
+	}
+	return g.inh[key{attr, node.Kind().String()}]
+}
+
+// Decorate wraps a tree so it's attributes can be evaluated. Attributes are
+// evaluated on demand and each is only evaluated once per node.
+func (g *Grammar) Decorate(root parlex.ParseNode) *Node {
+	return g.wrap(root, nil, -1)
+}
+
+func (g *Grammar) wrap(pn parlex.ParseNode, parent *Node, idx int) *Node {
+	return &Node{
+		ParseNode: pn,
+		g:         g,
+		parent:    parent,
+		idx:       idx,
+		children:  make([]*Node, pn.Children()),
+		vals:      make(map[string]interface{}),
+		active:    make(map[string]bool),
+	}
+}
+
+// Node wraps a parlex.ParseNode with it's attributes.
+type Node struct {
+	parlex.ParseNode
+	g        *Grammar
+	parent   *Node
+	idx      int
+	children []*Node
+	vals     map[string]interface{}
+	active   map[string]bool
+}
+
+// Up returns the parent Node, it will be nil at the root.
+func (n *Node) Up() *Node { return n.parent }
+
+// Down returns the child Node at cIdx. If cIdx is negative, it is relative to
+// the end.
+func (n *Node) Down(cIdx int) *Node {
+	if cIdx < 0 {
+		cIdx += len(n.children)
+	}
+	if n.children[cIdx] == nil {
+		n.children[cIdx] = n.g.wrap(n.ParseNode.Child(cIdx), n, cIdx)
+	}
+	return n.children[cIdx]
+}
+
+// Index of the node in it's parent, the root has an index of -1.
+func (n *Node) Index() int { return n.idx }
+
+// Get the value of an attribute, evaluating it if necessary. Get should be
+// used inside equations; it panics if the attribute cannot be evaluated and
+// the panic is recovered by Eval.
+func (n *Node) Get(attr string) interface{} {
+	if v, ok := n.vals[attr]; ok {
+		return v
+	}
+	if n.active[attr] {
+		panic(n.err(attr, ErrCycle))
+	}
+	n.active[attr] = true
+	defer delete(n.active, attr)
+
+	v := n.eval(attr)
+	n.vals[attr] = v
+	return v
+}
+
+func (n *Node) eval(attr string) interface{} {
+	if fn := n.g.synFor(attr, n.ParseNode); fn != nil {
+		return fn(n)
+	}
+	if n.parent == nil {
+		if v, ok := n.g.root[attr]; ok {
+			return v
+		}
+		panic(n.err(attr, ErrNoEquation))
+	}
+	if fn := n.g.inhFor(attr, n.parent.ParseNode); fn != nil {
+		return fn(n.parent, n.idx)
+	}
+	return n.parent.Get(attr)
+}
+
+func (n *Node) err(attr string, err error) *Error {
+	l, c := n.Pos()
+	return &Error{
+		Attr: attr,
+		Kind: n.Kind().String(),
+		Line: l,
+		Col:  c,
+		Err:  err,
+	}
+}
+
+// Eval returns the value of an attribute. If the attribute has a circular
+// dependency or is missing an equation, an *Error is returned.
+func (n *Node) Eval(attr string) (val interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+	return n.Get(attr), nil
+}
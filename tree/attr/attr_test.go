@@ -0,0 +1,140 @@
+package attr
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/adamcolton/parlex/tree"
+	"github.com/stretchr/testify/assert"
+)
+
+// env is an immutable scope of variables.
+type env struct {
+	name string
+	val  int
+	up   *env
+}
+
+func (e *env) lookup(name string) int {
+	for ; e != nil; e = e.up {
+		if e.name == name {
+			return e.val
+		}
+	}
+	return 0
+}
+
+func calc() *Grammar {
+	return New().
+		Root("env", (*env)(nil)).
+		Root("depth", 0).
+		Syn("val", "number", func(n *Node) interface{} {
+			i, _ := strconv.Atoi(n.Value())
+			return i
+		}).
+		Syn("val", "var", func(n *Node) interface{} {
+			return n.Get("env").(*env).lookup(n.Value())
+		}).
+		Syn("val", "op", func(n *Node) interface{} {
+			a, b := n.Down(0).Get("val").(int), n.Down(1).Get("val").(int)
+			if n.Value() == "*" {
+				return a * b
+			}
+			return a + b
+		}).
+		Syn("val", "let", func(n *Node) interface{} {
+			return n.Down(1).Get("val")
+		}).
+		// the bound expression does not see it's own binding
+		Inh("env", "let", func(n *Node, cIdx int) interface{} {
+			e := n.Get("env").(*env)
+			if cIdx == 0 {
+				return e
+			}
+			return &env{
+				name: n.Value(),
+				val:  n.Down(0).Get("val").(int),
+				up:   e,
+			}
+		}).
+		Inh("depth", "let", func(n *Node, cIdx int) interface{} {
+			return n.Get("depth").(int) + 1
+		}).
+		// a let whose body is a bare var is counted more deeply
+		Inh("depth", "let -> number var", func(n *Node, cIdx int) interface{} {
+			return n.Get("depth").(int) + 10
+		})
+}
+
+func TestCalc(t *testing.T) {
+	root, err := tree.New(`
+    let: "x" {
+      number: "2"
+      let: "y" {
+        op: "+" {
+          var: "x"
+          number: "3"
+        }
+        op: "*" {
+          var: "x"
+          var: "y"
+        }
+      }
+    }
+  `)
+	assert.NoError(t, err)
+
+	n := calc().Decorate(root)
+	v, err := n.Eval("val")
+	assert.NoError(t, err)
+	assert.Equal(t, 10, v)
+
+	mul := n.Down(1).Down(-1)
+	assert.Equal(t, "*", mul.Value())
+	assert.Equal(t, 2, mul.Get("depth"))
+	assert.Equal(t, "y", mul.Get("env").(*env).name)
+	assert.Equal(t, n, mul.Up().Up())
+	assert.Equal(t, 1, mul.Index())
+}
+
+func TestProduction(t *testing.T) {
+	root, err := tree.New(`
+    let: "x" {
+      number: "2"
+      var: "x"
+    }
+  `)
+	assert.NoError(t, err)
+
+	n := calc().Decorate(root)
+	assert.Equal(t, 2, n.Get("val"))
+	assert.Equal(t, 10, n.Down(1).Get("depth"))
+}
+
+func TestErrors(t *testing.T) {
+	root, err := tree.New(`
+    A {
+      B
+    }
+  `)
+	assert.NoError(t, err)
+
+	g := New().
+		Syn("a", "A", func(n *Node) interface{} {
+			return n.Down(0).Get("b")
+		}).
+		Syn("b", "B", func(n *Node) interface{} {
+			return n.Up().Get("a")
+		})
+	_, err = g.Decorate(root).Eval("a")
+	if assert.IsType(t, &Error{}, err) {
+		assert.Equal(t, ErrCycle, err.(*Error).Err)
+		assert.Equal(t, "A", err.(*Error).Kind)
+	}
+
+	_, err = g.Decorate(root).Down(0).Eval("c")
+	if assert.IsType(t, &Error{}, err) {
+		assert.Equal(t, ErrNoEquation, err.(*Error).Err)
+		assert.Equal(t, "A) c: No equation for attribute", err.Error())
+	}
+}
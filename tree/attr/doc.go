@@ -0,0 +1,21 @@
+// Package attr evaluates attribute grammars over parse trees. Synthesized
+// attributes are computed from a node and it's children, inherited attributes
+// are passed from a parent to it's children. Equations are declared per kind or
+// per production and are evaluated on demand when an attribute is requested.
+// Each attribute is evaluated at most once per node and circular dependencies
+// are reported as errors.
+//
+// An inherited attribute without an equation on the parent is copied from the
+// parent, so an environment only needs an equation where it changes.
+//
+//	g := attr.New().
+//	  Root("env", Env{}).
+//	  Syn("val", "number", func(n *attr.Node) interface{} {
+//	    f, _ := strconv.ParseFloat(n.Value(), 64)
+//	    return f
+//	  }).
+//	  Syn("val", "E -> E op E", func(n *attr.Node) interface{} {
+//	    return apply(n.Down(1).Value(), n.Down(0).Get("val"), n.Down(2).Get("val"))
+//	  })
+//	val, err := g.Decorate(root).Eval("val")
+package attr
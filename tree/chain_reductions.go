@@ -1,5 +1,9 @@
 package tree
 
+import (
+	"regexp"
+)
+
 // Chain two reductions so that the first will be called on the node, then the
 // second. If one of the reductions is nil, the other is returned.
 func Chain(r1, r2 Reduction) Reduction {
@@ -46,6 +50,52 @@ func ChildIs(cIdx int, kind string) Condition {
 	}
 }
 
+// ChildCountIs returns true if the node has n children.
+func ChildCountIs(n int) Condition {
+	return func(node *PN) bool {
+		return len(node.C) == n
+	}
+}
+
+// ValueMatches returns true if the node's value matches the regular
+// expression.
+func ValueMatches(re *regexp.Regexp) Condition {
+	return func(node *PN) bool {
+		return re.MatchString(node.Value())
+	}
+}
+
+// And returns true if all the conditions are true.
+func And(conditions ...Condition) Condition {
+	return func(node *PN) bool {
+		for _, c := range conditions {
+			if !c(node) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns true if any of the conditions are true.
+func Or(conditions ...Condition) Condition {
+	return func(node *PN) bool {
+		for _, c := range conditions {
+			if c(node) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns true if the condition is false.
+func Not(condition Condition) Condition {
+	return func(node *PN) bool {
+		return !condition(node)
+	}
+}
+
 // PromoteChild removes the node with the child at cIdx and replaces it's own
 // lexeme with the value. The grandchildren are spliced into the replaced childs
 // position. The cIdx value uses GetIdx.
@@ -161,3 +211,77 @@ func PromoteGrandChildren(node *PN) {
 func PromoteSingleChild(node *PN) {
 	node.PromoteSingleChild()
 }
+
+// Rename changes the kind of the node.
+func (r Reduction) Rename(kind string) Reduction {
+	return Chain(r, Rename(kind))
+}
+
+// SetValue sets the value of the node.
+func (r Reduction) SetValue(val string) Reduction {
+	return Chain(r, SetValue(val))
+}
+
+// ConcatChildValues sets the value of the node to the values of it's children
+// joined by sep and removes the children.
+func (r Reduction) ConcatChildValues(sep string) Reduction {
+	return Chain(r, ConcatChildValues(sep))
+}
+
+// Collapse merges each run of adjacent leaves with the same kind into one leaf.
+func (r Reduction) Collapse(kinds ...string) Reduction {
+	return Chain(r, Collapse(kinds...))
+}
+
+// Flatten replaces any child of the given kind with it's children, repeatedly.
+func (r Reduction) Flatten(kind string) Reduction {
+	return Chain(r, Flatten(kind))
+}
+
+// Wrap replaces the node with a node of the given kind that has the original
+// node as it's only child.
+func (r Reduction) Wrap(kind string) Reduction {
+	return Chain(r, Wrap(kind))
+}
+
+// SwapChildren swaps the children at i and j.
+func (r Reduction) SwapChildren(i, j int) Reduction {
+	return Chain(r, SwapChildren(i, j))
+}
+
+// Rename changes the kind of the node.
+func Rename(kind string) Reduction {
+	return func(node *PN) { node.Rename(kind) }
+}
+
+// SetValue sets the value of the node.
+func SetValue(val string) Reduction {
+	return func(node *PN) { node.SetValue(val) }
+}
+
+// ConcatChildValues sets the value of the node to the values of it's children
+// joined by sep and removes the children.
+func ConcatChildValues(sep string) Reduction {
+	return func(node *PN) { node.ConcatChildValues(sep) }
+}
+
+// Collapse merges each run of adjacent leaves with the same kind into one leaf.
+func Collapse(kinds ...string) Reduction {
+	return func(node *PN) { node.Collapse(kinds...) }
+}
+
+// Flatten replaces any child of the given kind with it's children, repeatedly.
+func Flatten(kind string) Reduction {
+	return func(node *PN) { node.Flatten(kind) }
+}
+
+// Wrap replaces the node with a node of the given kind that has the original
+// node as it's only child.
+func Wrap(kind string) Reduction {
+	return func(node *PN) { node.Wrap(kind) }
+}
+
+// SwapChildren swaps the children at i and j.
+func SwapChildren(i, j int) Reduction {
+	return func(node *PN) { node.SwapChildren(i, j) }
+}
@@ -0,0 +1,62 @@
+package tree
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/adamcolton/parlex"
+)
+
+// ErrNotLossless is returned by ParseCST if the parse tree does not reproduce
+// the input. This generally means the lexer was not set to keep trivia.
+var ErrNotLossless = errors.New("Parse tree does not reproduce input")
+
+// ParseCST lexes and parses the input without any reductions and confirms that
+// the resulting concrete syntax tree reproduces the input exactly with Print.
+// The lexer must keep trivia for this to succeed.
+func ParseCST(input string, lexer parlex.Lexer, parser parlex.Parser) (*PN, error) {
+	lexemes := lexer.Lex(input)
+	if lexemes == nil {
+		return nil, parlex.ErrCouldNotLex
+	}
+	if errs := parlex.LexErrors(lexemes); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	node := parser.Parse(lexemes)
+	if node == nil {
+		return nil, parlex.ErrCouldNotParse
+	}
+	pn, ok := node.(*PN)
+	if !ok {
+		pn = Clone(node)
+	}
+	if Print(pn) != input {
+		return nil, ErrNotLossless
+	}
+	return pn, nil
+}
+
+// Print regenerates source text from a concrete syntax tree. Each leaf
+// contributes it's leading trivia, source and trailing trivia in order; a leaf
+// that does not know it's source, such as one added while editing the tree,
+// contributes it's value. The lexemes of nodes with children are not printed,
+// so Print should be used on trees that have not been reduced.
+func Print(node parlex.ParseNode) string {
+	var buf strings.Builder
+	printNode(node, &buf)
+	return buf.String()
+}
+
+func printNode(node parlex.ParseNode, buf *strings.Builder) {
+	if node == nil {
+		return
+	}
+	ln := node.Children()
+	if ln == 0 {
+		buf.WriteString(parlex.Source(node))
+		return
+	}
+	for i := 0; i < ln; i++ {
+		printNode(node.Child(i), buf)
+	}
+}
@@ -0,0 +1,98 @@
+package tree
+
+import (
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/lexeme"
+	"github.com/adamcolton/parlex/lexer/simplelexer"
+	"github.com/stretchr/testify/assert"
+)
+
+// listParser places every lexeme under a single List node.
+type listParser struct{}
+
+func (listParser) Parse(lxs []parlex.Lexeme) parlex.ParseNode {
+	root := &PN{
+		Lexeme: lexeme.String("List"),
+	}
+	for _, lx := range lxs {
+		root.C = append(root.C, &PN{
+			Lexeme: lx,
+			P:      root,
+		})
+	}
+	return root
+}
+
+const cstLexer = `
+  word    /\w+/
+  comma   /,/
+  comment /\/\/[^\n]*/ -
+  space   /\s+/ -
+`
+
+func TestParseCST(t *testing.T) {
+	lxr, err := simplelexer.New(cstLexer)
+	assert.NoError(t, err)
+	input := "  alpha, // first\n  beta ,gamma  "
+
+	_, err = ParseCST(input, lxr, listParser{})
+	assert.Equal(t, ErrNotLossless, err)
+
+	lxr.KeepTrivia()
+	root, err := ParseCST(input, lxr, listParser{})
+	assert.NoError(t, err)
+	assert.Equal(t, input, Print(root))
+
+	// rename beta
+	root.C[2].SetValue("delta")
+	assert.Equal(t, "  alpha, // first\n  delta ,gamma  ", Print(root))
+
+	// remove gamma and the comma before it
+	root.RemoveChildren(3, 4)
+	assert.Equal(t, "  alpha, // first\n  delta ", Print(root))
+
+	// insert a new word with leading space
+	ins := lexeme.String("word").Set("omega")
+	ins.T = &lexeme.Trivia{
+		Src:  "omega",
+		Lead: []parlex.Lexeme{lexeme.String("space").Set(" ")},
+	}
+	assert.True(t, root.InsertChild(3, &PN{Lexeme: lexeme.String("comma").Set(",")}, &PN{Lexeme: ins}))
+	assert.Equal(t, "  alpha, // first\n  delta , omega", Print(root))
+}
+
+func TestCollapseTrivia(t *testing.T) {
+	lxr, err := simplelexer.New(cstLexer)
+	assert.NoError(t, err)
+	lxr.KeepTrivia()
+	input := "  alpha beta // first\n  gamma, delta  "
+	root, err := ParseCST(input, lxr, listParser{})
+	assert.NoError(t, err)
+
+	root.Collapse("word")
+	if assert.Len(t, root.C, 3) {
+		assert.Equal(t, "alphabetagamma", root.C[0].Value())
+		assert.Equal(t, "  ", parlex.Source(root.C[0].Leading()...))
+	}
+	assert.Equal(t, input, Print(root))
+}
+
+func TestInsertChild(t *testing.T) {
+	pn, err := New(`
+    A {
+      B
+      C
+    }
+  `)
+	assert.NoError(t, err)
+	assert.True(t, pn.InsertChild(-1, &PN{Lexeme: lexeme.String("X")}))
+	assert.True(t, pn.InsertChild(3, &PN{Lexeme: lexeme.String("Y")}))
+	assert.False(t, pn.InsertChild(5, &PN{Lexeme: lexeme.String("Z")}))
+	assert.Equal(t, "A {\n\tB\n\tX\n\tC\n\tY\n}\n", pn.String())
+	assert.Equal(t, pn, pn.C[1].P)
+
+	assert.True(t, pn.ReplaceChild(0, &PN{Lexeme: lexeme.String("W")}))
+	assert.Equal(t, "W", pn.C[0].Kind().String())
+}
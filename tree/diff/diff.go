@@ -0,0 +1,215 @@
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/tree"
+)
+
+// OpKind is the kind of change in an Op.
+type OpKind byte
+
+// OpKinds
+const (
+	Insert OpKind = iota
+	Delete
+	Update
+	Move
+)
+
+func (k OpKind) String() string {
+	switch k {
+	case Insert:
+		return "insert"
+	case Delete:
+		return "delete"
+	case Update:
+		return "update"
+	case Move:
+		return "move"
+	}
+	return "unknown"
+}
+
+// Path is the list of child indexes from the root to a node.
+type Path []int
+
+func (p Path) String() string {
+	strs := make([]string, len(p))
+	for i, idx := range p {
+		strs[i] = strconv.Itoa(idx)
+	}
+	return "/" + strings.Join(strs, "/")
+}
+
+// Op is a single change between two trees. From is the path in the original
+// tree and To is the path in the new tree. An Insert only has To, a Delete
+// only has From.
+type Op struct {
+	Kind     OpKind
+	From, To Path
+	A, B     parlex.ParseNode
+}
+
+func (op Op) String() string {
+	switch op.Kind {
+	case Insert:
+		return fmt.Sprintf("insert %s %s", op.To, describe(op.B))
+	case Delete:
+		return fmt.Sprintf("delete %s %s", op.From, describe(op.A))
+	case Update:
+		return fmt.Sprintf("update %s %s -> %s", op.From, describe(op.A), describe(op.B))
+	}
+	return fmt.Sprintf("move %s -> %s %s", op.From, op.To, describe(op.A))
+}
+
+func describe(n parlex.ParseNode) string {
+	if v := n.Value(); v != "" {
+		return fmt.Sprintf("%s: %q", n.Kind().String(), v)
+	}
+	return n.Kind().String()
+}
+
+// Diff returns the changes needed to turn a into b. The children of matching
+// nodes are aligned with a longest common subsequence of equal subtrees;
+// unaligned children of the same kind are compared recursively and anything
+// left is inserted or deleted. A deleted subtree that is equal to an inserted
+// subtree is reported as a Move.
+func Diff(a, b parlex.ParseNode, opts ...tree.EqualOption) []Op {
+	switch {
+	case a == nil && b == nil:
+		return nil
+	case a == nil:
+		return []Op{{Kind: Insert, To: Path{}, B: b}}
+	case b == nil:
+		return []Op{{Kind: Delete, From: Path{}, A: a}}
+	}
+	d := &differ{opts: opts}
+	d.node(a, b, Path{}, Path{})
+	return d.moves()
+}
+
+type differ struct {
+	opts []tree.EqualOption
+	ops  []Op
+}
+
+func (d *differ) node(a, b parlex.ParseNode, from, to Path) {
+	if !tree.NodeEqual(a, b, d.opts...) {
+		if a.Kind().String() != b.Kind().String() {
+			d.ops = append(d.ops, Op{Kind: Delete, From: from, A: a}, Op{Kind: Insert, To: to, B: b})
+			return
+		}
+		d.ops = append(d.ops, Op{Kind: Update, From: from, To: to, A: a, B: b})
+	}
+	d.children(a, b, from, to)
+}
+
+func (d *differ) children(a, b parlex.ParseNode, from, to Path) {
+	la, lb := a.Children(), b.Children()
+	match := d.lcs(a, b)
+
+	i, j := 0, 0
+	for _, m := range append(match, [2]int{la, lb}) {
+		d.gap(a, b, from, to, i, m[0], j, m[1])
+		i, j = m[0]+1, m[1]+1
+	}
+}
+
+// gap handles the unaligned children a[i:iEnd] and b[j:jEnd].
+func (d *differ) gap(a, b parlex.ParseNode, from, to Path, i, iEnd, j, jEnd int) {
+	for i < iEnd || j < jEnd {
+		if i < iEnd && j < jEnd && a.Child(i).Kind().String() == b.Child(j).Kind().String() {
+			d.node(a.Child(i), b.Child(j), extend(from, i), extend(to, j))
+			i++
+			j++
+			continue
+		}
+		if i < iEnd && (j == jEnd || !hasKind(b, j, jEnd, a.Child(i).Kind().String())) {
+			d.ops = append(d.ops, Op{Kind: Delete, From: extend(from, i), A: a.Child(i)})
+			i++
+			continue
+		}
+		d.ops = append(d.ops, Op{Kind: Insert, To: extend(to, j), B: b.Child(j)})
+		j++
+	}
+}
+
+func hasKind(n parlex.ParseNode, start, end int, kind string) bool {
+	for ; start < end; start++ {
+		if n.Child(start).Kind().String() == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func extend(p Path, idx int) Path {
+	out := make(Path, len(p)+1)
+	copy(out, p)
+	out[len(p)] = idx
+	return out
+}
+
+// lcs returns the pairs of child indexes of the longest common subsequence of
+// equal children.
+func (d *differ) lcs(a, b parlex.ParseNode) [][2]int {
+	la, lb := a.Children(), b.Children()
+	t := make([][]int, la+1)
+	for i := range t {
+		t[i] = make([]int, lb+1)
+	}
+	for i := la - 1; i >= 0; i-- {
+		for j := lb - 1; j >= 0; j-- {
+			if tree.Equal(a.Child(i), b.Child(j), d.opts...) {
+				t[i][j] = t[i+1][j+1] + 1
+			} else if t[i+1][j] >= t[i][j+1] {
+				t[i][j] = t[i+1][j]
+			} else {
+				t[i][j] = t[i][j+1]
+			}
+		}
+	}
+	var out [][2]int
+	for i, j := 0, 0; i < la && j < lb; {
+		switch {
+		case tree.Equal(a.Child(i), b.Child(j), d.opts...):
+			out = append(out, [2]int{i, j})
+			i++
+			j++
+		case t[i+1][j] >= t[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return out
+}
+
+// moves pairs deletes and inserts of equal subtrees.
+func (d *differ) moves() []Op {
+	used := make([]bool, len(d.ops))
+	var out []Op
+	for i, del := range d.ops {
+		if used[i] || del.Kind != Delete {
+			continue
+		}
+		for j, ins := range d.ops {
+			if used[j] || ins.Kind != Insert || !tree.Equal(del.A, ins.B, d.opts...) {
+				continue
+			}
+			used[i], used[j] = true, true
+			d.ops[i] = Op{Kind: Move, From: del.From, To: ins.To, A: del.A, B: ins.B}
+			break
+		}
+	}
+	for i, op := range d.ops {
+		if op.Kind == Move || !used[i] {
+			out = append(out, op)
+		}
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/adamcolton/parlex/lexeme"
+	"github.com/adamcolton/parlex/tree"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	a, err := tree.New(`
+    E {
+      int: "1"
+      op: "+"
+      P {
+        int: "2"
+      }
+    }
+  `)
+	assert.NoError(t, err)
+
+	assert.Len(t, Diff(a, tree.Clone(a)), 0)
+
+	tt := map[string]struct {
+		b        string
+		expected []string
+	}{
+		"update": {
+			b: `
+        E {
+          int: "1"
+          op: "-"
+          P {
+            int: "2"
+          }
+        }
+      `,
+			expected: []string{`update /1 op: "+" -> op: "-"`},
+		},
+		"insert": {
+			b: `
+        E {
+          int: "1"
+          op: "+"
+          P {
+            int: "2"
+            int: "3"
+          }
+        }
+      `,
+			expected: []string{`insert /2/1 int: "3"`},
+		},
+		"delete": {
+			b: `
+        E {
+          int: "1"
+          P {
+            int: "2"
+          }
+        }
+      `,
+			expected: []string{`delete /1 op: "+"`},
+		},
+		"move": {
+			b: `
+        E {
+          P {
+            int: "2"
+          }
+          int: "1"
+          op: "+"
+        }
+      `,
+			expected: []string{`move /2 -> /0 P`},
+		},
+		"kind": {
+			b: `
+        E {
+          int: "1"
+          op: "+"
+          Q {
+            int: "2"
+          }
+        }
+      `,
+			expected: []string{`delete /2 P`, `insert /2 Q`},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			b, err := tree.New(tc.b)
+			assert.NoError(t, err)
+			ops := Diff(a, b)
+			strs := make([]string, len(ops))
+			for i, op := range ops {
+				strs[i] = op.String()
+			}
+			assert.Equal(t, tc.expected, strs)
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a, err := tree.New(`
+    E {
+      int: "1"
+      op: "+"
+      int: "2"
+    }
+  `)
+	assert.NoError(t, err)
+	b := tree.Clone(a)
+	assert.Equal(t, "", Unified(a, b))
+
+	b.C[2].SetValue("3")
+	u := Unified(a, b)
+	assert.Contains(t, u, "--- a\n+++ b\n@@ -1,5 +1,5 @@\n")
+	assert.Contains(t, u, "-\tint: \"2\"\n+\tint: \"3\"\n")
+
+	// only the position differs
+	b = tree.Clone(a)
+	b.C[2].Lexeme.(*lexeme.Lexeme).L = 2
+	b.C[2].Lexeme.(*lexeme.Lexeme).C = 4
+	assert.Equal(t, "--- a\n+++ b\nupdate /2 int: \"2\" @-1:0 -> int: \"2\" @2:4\n", Unified(a, b))
+	assert.Equal(t, "", Unified(a, b, tree.IgnorePos))
+}
@@ -0,0 +1,7 @@
+// Package diff compares parse trees. Diff returns the structural changes
+// between two trees as inserts, deletes, updates and moves with the path to
+// each node. Unified renders the difference in the familiar unified diff
+// format using the tree.PN String representation, which gives far more useful
+// test failures than comparing the strings directly. When the trees only differ
+// by position, Unified lists the changes with the positions of the nodes.
+package diff
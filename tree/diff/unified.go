@@ -0,0 +1,168 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/tree"
+)
+
+// Context is the number of unchanged lines shown around each change by
+// Unified.
+var Context = 3
+
+const header = "--- a\n+++ b\n"
+
+// Unified renders the difference between two trees as a unified diff of their
+// tree.PN String representations. If the trees are equal, an empty string is
+// returned. The String representation does not include positions, so if the
+// trees only differ by position the Ops from Diff are listed with the
+// positions of their nodes instead.
+func Unified(a, b parlex.ParseNode, opts ...tree.EqualOption) string {
+	if tree.Equal(a, b, opts...) {
+		return ""
+	}
+	if u := Lines(lines(a), lines(b)); u != header {
+		return u
+	}
+	var buf strings.Builder
+	buf.WriteString(header)
+	for _, op := range Diff(a, b, opts...) {
+		switch op.Kind {
+		case Insert:
+			fmt.Fprintf(&buf, "insert %s %s\n", op.To, describePos(op.B))
+		case Delete:
+			fmt.Fprintf(&buf, "delete %s %s\n", op.From, describePos(op.A))
+		case Update:
+			fmt.Fprintf(&buf, "update %s %s -> %s\n", op.From, describePos(op.A), describePos(op.B))
+		default:
+			fmt.Fprintf(&buf, "move %s -> %s %s\n", op.From, op.To, describePos(op.A))
+		}
+	}
+	return buf.String()
+}
+
+// describePos describes a node with it's position as line:col, followed by the
+// end position if it is known.
+func describePos(n parlex.ParseNode) string {
+	l, c := n.Pos()
+	pos := fmt.Sprintf("%d:%d", l, c)
+	if el, ec, ok := parlex.End(n); ok {
+		pos += fmt.Sprintf("-%d:%d", el, ec)
+	}
+	return describe(n) + " @" + pos
+}
+
+func lines(n parlex.ParseNode) []string {
+	if n == nil {
+		return nil
+	}
+	pn, ok := n.(*tree.PN)
+	if !ok {
+		pn = tree.Clone(n)
+	}
+	return strings.Split(strings.TrimSuffix(pn.String(), "\n"), "\n")
+}
+
+type line struct {
+	op   byte
+	text string
+	a, b int
+}
+
+// Lines produces a unified diff of two lists of lines.
+func Lines(a, b []string) string {
+	ls := lineDiff(a, b)
+
+	var buf strings.Builder
+	buf.WriteString(header)
+	for start := 0; start < len(ls); {
+		// find the next change
+		for start < len(ls) && ls[start].op == ' ' {
+			start++
+		}
+		if start == len(ls) {
+			break
+		}
+		// extend the hunk until there are more than 2*Context unchanged lines
+		end, same := start, 0
+		for i := start; i < len(ls) && same <= 2*Context; i++ {
+			if ls[i].op == ' ' {
+				same++
+			} else {
+				same = 0
+				end = i + 1
+			}
+		}
+		from, to := start-Context, end+Context
+		if from < 0 {
+			from = 0
+		}
+		if to > len(ls) {
+			to = len(ls)
+		}
+		writeHunk(&buf, ls[from:to])
+		start = to
+	}
+	return buf.String()
+}
+
+func writeHunk(buf *strings.Builder, ls []line) {
+	aStart, bStart, aLen, bLen := -1, -1, 0, 0
+	for _, l := range ls {
+		if l.op != '+' {
+			if aStart == -1 {
+				aStart = l.a
+			}
+			aLen++
+		}
+		if l.op != '-' {
+			if bStart == -1 {
+				bStart = l.b
+			}
+			bLen++
+		}
+	}
+	fmt.Fprintf(buf, "@@ -%d,%d +%d,%d @@\n", aStart+1, aLen, bStart+1, bLen)
+	for _, l := range ls {
+		buf.WriteByte(l.op)
+		buf.WriteString(l.text)
+		buf.WriteByte('\n')
+	}
+}
+
+func lineDiff(a, b []string) []line {
+	t := make([][]int, len(a)+1)
+	for i := range t {
+		t[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				t[i][j] = t[i+1][j+1] + 1
+			} else if t[i+1][j] >= t[i][j+1] {
+				t[i][j] = t[i+1][j]
+			} else {
+				t[i][j] = t[i][j+1]
+			}
+		}
+	}
+	var out []line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, line{' ', a[i], i, j})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && t[i+1][j] >= t[i][j+1]):
+			out = append(out, line{'-', a[i], i, j})
+			i++
+		default:
+			out = append(out, line{'+', b[j], i, j})
+			j++
+		}
+	}
+	return out
+}
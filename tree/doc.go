@@ -1,3 +1,8 @@
 // Package tree provides a concrete implementation of parlex.ParseNode and
 // parlex.Reduce. It includes many useful functions to simplify tree reduction.
+//
+// Positions are preserved through Clone, Reduce and the reductions. When a
+// reduction merges a child into it's parent, such as PromoteChildValue or
+// ReplaceWithChild, the resulting node spans the union of both so that errors
+// found after reduction still point to the source.
 package tree
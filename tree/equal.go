@@ -0,0 +1,77 @@
+package tree
+
+import (
+	"github.com/adamcolton/parlex"
+)
+
+// EqualOption changes what is compared by Equal.
+type EqualOption uint8
+
+// EqualOptions
+const (
+	// IgnorePos does not compare the start and end positions of nodes.
+	IgnorePos EqualOption = 1 << iota
+	// IgnoreValues does not compare the values of nodes.
+	IgnoreValues
+)
+
+func mergeOptions(opts []EqualOption) EqualOption {
+	var o EqualOption
+	for _, opt := range opts {
+		o |= opt
+	}
+	return o
+}
+
+// Equal compares two trees. Nodes are equal if they have the same kind, value,
+// position and equal children. Options can be used to ignore positions or
+// values.
+func Equal(a, b parlex.ParseNode, opts ...EqualOption) bool {
+	return equal(a, b, mergeOptions(opts))
+}
+
+func equal(a, b parlex.ParseNode, o EqualOption) bool {
+	if !NodeEqual(a, b, o) {
+		return false
+	}
+	if a == nil {
+		return true
+	}
+	ln := a.Children()
+	if ln != b.Children() {
+		return false
+	}
+	for i := 0; i < ln; i++ {
+		if !equal(a.Child(i), b.Child(i), o) {
+			return false
+		}
+	}
+	return true
+}
+
+// NodeEqual compares two nodes without comparing their children.
+func NodeEqual(a, b parlex.ParseNode, opts ...EqualOption) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	o := mergeOptions(opts)
+	if a.Kind().String() != b.Kind().String() {
+		return false
+	}
+	if o&IgnoreValues == 0 && a.Value() != b.Value() {
+		return false
+	}
+	if o&IgnorePos == 0 {
+		al, ac := a.Pos()
+		bl, bc := b.Pos()
+		if al != bl || ac != bc {
+			return false
+		}
+		ael, aec, _ := parlex.End(a)
+		bel, bec, _ := parlex.End(b)
+		if ael != bel || aec != bec {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package tree
+
+import (
+	"testing"
+
+	"github.com/adamcolton/parlex/lexeme"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEqual(t *testing.T) {
+	a, err := New(`
+    E {
+      int: "1"
+      op: "+"
+      int: "2"
+    }
+  `)
+	assert.NoError(t, err)
+	b := Clone(a)
+	assert.True(t, Equal(a, b))
+
+	b.C[2].SetValue("3")
+	assert.False(t, Equal(a, b))
+	assert.True(t, Equal(a, b, IgnoreValues))
+
+	b.C[2].SetValue("2")
+	b.C[2].Lexeme.(*lexeme.Lexeme).At(1, 5)
+	assert.False(t, Equal(a, b))
+	assert.True(t, Equal(a, b, IgnorePos))
+
+	b.RemoveChild(2)
+	assert.False(t, Equal(a, b, IgnorePos, IgnoreValues))
+	assert.True(t, NodeEqual(a, b))
+	assert.True(t, Equal(nil, nil))
+	assert.False(t, Equal(a, nil))
+}
@@ -1,16 +1,26 @@
 package tree
 
 import (
+	"strings"
+
+	"github.com/adamcolton/parlex"
 	"github.com/adamcolton/parlex/lexeme"
+	"github.com/adamcolton/parlex/symbol/stringsymbol"
 )
 
-// ReplaceWithChild replaces the node with the child at cIdx.
+// ReplaceWithChild replaces the node with the child at cIdx. The span of the
+// node is the union of it's own span and the child's.
 func (p *PN) ReplaceWithChild(cIdx int) bool {
 	cIdx, _, ok := p.GetIdx(cIdx)
 	if !ok {
 		return false
 	}
-	*p = *(p.C[cIdx])
+	ch := p.C[cIdx]
+	p.Lexeme = lexeme.Copy(ch.Lexeme).Span(p.Lexeme)
+	p.C = ch.C
+	for _, c := range p.C {
+		c.P = p
+	}
 	return true
 }
 
@@ -87,7 +97,8 @@ func (p *PN) PromoteSingleChild() bool {
 // PromoteChildValue returns a Reduction that will replace the value of the node
 // with the value from the child at cIdx and remove the child at cIdx. If cIdx
 // is negative, it will find the child relative to the end. If cIdx is out of
-// bounds, no action will be taken.
+// bounds, no action will be taken. The span of the node is the union of it's
+// own span and the child's.
 func (p *PN) PromoteChildValue(cIdx int) {
 	l := len(p.C)
 	if cIdx < 0 {
@@ -95,7 +106,7 @@ func (p *PN) PromoteChildValue(cIdx int) {
 	}
 	if cIdx >= 0 && l > cIdx {
 		ch := p.C[cIdx]
-		p.Lexeme = lexeme.New(p.Kind()).Set(ch.Value()).At(ch.Pos())
+		p.Lexeme = lexeme.New(p.Kind()).Set(ch.Value()).At(ch.Pos()).Span(ch.Lexeme, p.Lexeme)
 	}
 	p.RemoveChild(cIdx)
 }
@@ -117,6 +128,9 @@ func (p *PN) PromoteChildrenOf(cIdx int) bool {
 	if !ok {
 		return false
 	}
+	for _, c := range p.C[cIdx].C {
+		c.P = p
+	}
 	if cIdx == l-1 {
 		p.C = append(p.C[:cIdx], p.C[cIdx].C...)
 	} else {
@@ -144,14 +158,15 @@ func (p *PN) PromoteGrandChildren() {
 
 // PromoteChild removes the node with the child at cIdx and replaces it's own
 // lexeme with the value. The grandchildren are spliced into the replaced childs
-// position. The cIdx value uses GetIdx.
+// position. The cIdx value uses GetIdx. The span of the node is the union of
+// it's own span and the child's.
 func (p *PN) PromoteChild(cIdx int) bool {
 	cIdx, l, ok := p.GetIdx(cIdx)
 	if !ok {
 		return false
 	}
 
-	p.Lexeme = p.C[cIdx].Lexeme
+	p.Lexeme = lexeme.Copy(p.C[cIdx].Lexeme).Span(p.Lexeme)
 	tail := p.C[cIdx].C
 	if cIdx+1 < l {
 		tail = append(tail, p.C[cIdx+1:]...)
@@ -162,3 +177,177 @@ func (p *PN) PromoteChild(cIdx int) bool {
 	}
 	return true
 }
+
+// InsertChild inserts children before the child at cIdx. If cIdx is negative,
+// it is relative to the end, so -1 inserts before the last child. If cIdx is
+// equal to the number of children, the children are appended. It returns false
+// if cIdx is out of bounds.
+func (p *PN) InsertChild(cIdx int, children ...*PN) bool {
+	l := len(p.C)
+	if cIdx < 0 {
+		cIdx = l + cIdx
+	}
+	if cIdx < 0 || cIdx > l {
+		return false
+	}
+	for _, c := range children {
+		c.P = p
+	}
+	newC := make([]*PN, 0, l+len(children))
+	newC = append(newC, p.C[:cIdx]...)
+	newC = append(newC, children...)
+	p.C = append(newC, p.C[cIdx:]...)
+	return true
+}
+
+// ReplaceChild replaces the child at cIdx with child. The cIdx value uses
+// GetIdx.
+func (p *PN) ReplaceChild(cIdx int, child *PN) bool {
+	cIdx, _, ok := p.GetIdx(cIdx)
+	if !ok {
+		return false
+	}
+	child.P = p
+	p.C[cIdx] = child
+	return true
+}
+
+// SetValue sets the value of the node. If the lexeme knows it's source, the
+// source is also set so that Print will use the new value.
+func (p *PN) SetValue(val string) {
+	lx := lexeme.Copy(p.Lexeme)
+	lx.V = val
+	if lx.T != nil {
+		t := *lx.T
+		t.Src = val
+		lx.T = &t
+	}
+	p.Lexeme = lx
+}
+
+// Rename changes the kind of the node.
+func (p *PN) Rename(kind string) {
+	lx := lexeme.Copy(p.Lexeme)
+	lx.K = stringsymbol.Symbol(kind)
+	p.Lexeme = lx
+}
+
+// ConcatChildValues sets the value of the node to the values of it's children
+// joined by sep and removes the children. The span of the node is the union of
+// it's own span and the children's.
+func (p *PN) ConcatChildValues(sep string) {
+	lx := lexeme.Copy(p.Lexeme)
+	lx.T = nil
+	vals := make([]string, len(p.C))
+	for i, c := range p.C {
+		vals[i] = c.Value()
+		lx.Span(c.Lexeme)
+	}
+	lx.V = strings.Join(vals, sep)
+	p.Lexeme = lx
+	p.C = nil
+}
+
+// Collapse merges each run of adjacent children with the same kind and no
+// children of their own into a single child, joining their values. If kinds
+// are given, only runs of those kinds are merged. This is useful after a
+// scannerless parse, where every rune is a separate leaf. The merged child
+// keeps the leading trivia of the first leaf and the trailing trivia of the
+// last; any trivia between them becomes part of it's source.
+func (p *PN) Collapse(kinds ...string) {
+	var only map[string]bool
+	if len(kinds) > 0 {
+		only = make(map[string]bool, len(kinds))
+		for _, k := range kinds {
+			only[k] = true
+		}
+	}
+	cs := make([]*PN, 0, len(p.C))
+	for _, c := range p.C {
+		kind := c.Kind().String()
+		ln := len(cs)
+		if ln == 0 || len(c.C) > 0 || len(cs[ln-1].C) > 0 || cs[ln-1].Kind().String() != kind || (only != nil && !only[kind]) {
+			cs = append(cs, c)
+			continue
+		}
+		lx := lexeme.Copy(cs[ln-1].Lexeme)
+		lx.T = joinTrivia(lx, c.Lexeme)
+		lx.V += c.Value()
+		cs[ln-1].Lexeme = lx.Span(c.Lexeme)
+	}
+	p.C = cs
+}
+
+// joinTrivia returns the trivia for a followed by b, or nil if neither has
+// trivia.
+func joinTrivia(a *lexeme.Lexeme, b parlex.Lexeme) *lexeme.Trivia {
+	bt, ok := b.(parlex.Trivia)
+	var bSrc string
+	if ok {
+		bSrc, ok = bt.Source()
+	}
+	if a.T == nil && !ok {
+		return nil
+	}
+	t := &lexeme.Trivia{
+		Src: a.Value(),
+	}
+	if a.T != nil {
+		t.Src = a.T.Src + parlex.Source(a.T.Trail...)
+		t.Lead = a.T.Lead
+	}
+	if ok {
+		t.Src += parlex.Source(bt.Leading()...) + bSrc
+		t.Trail = bt.Trailing()
+	} else {
+		t.Src += b.Value()
+	}
+	return t
+}
+
+// Flatten replaces any child of the given kind with it's children. This is
+// repeated for the spliced in children so nested nodes of that kind are
+// completely flattened.
+func (p *PN) Flatten(kind string) {
+	cs := make([]*PN, 0, len(p.C))
+	var flatten func(children []*PN)
+	flatten = func(children []*PN) {
+		for _, c := range children {
+			if c.Kind().String() == kind {
+				flatten(c.C)
+				continue
+			}
+			c.P = p
+			cs = append(cs, c)
+		}
+	}
+	flatten(p.C)
+	p.C = cs
+}
+
+// Wrap replaces the node with a node of the given kind that has the original
+// node as it's only child. The new node has the span of the original.
+func (p *PN) Wrap(kind string) {
+	inner := &PN{
+		Lexeme: p.Lexeme,
+		P:      p,
+		C:      p.C,
+	}
+	for _, c := range inner.C {
+		c.P = inner
+	}
+	p.Lexeme = lexeme.New(stringsymbol.Symbol(kind)).Span(inner.Lexeme)
+	p.C = []*PN{inner}
+}
+
+// SwapChildren swaps the children at i and j. Both use GetIdx. It returns
+// false if either is out of bounds.
+func (p *PN) SwapChildren(i, j int) bool {
+	i, _, iOk := p.GetIdx(i)
+	j, _, jOk := p.GetIdx(j)
+	if !iOk || !jOk {
+		return false
+	}
+	p.C[i], p.C[j] = p.C[j], p.C[i]
+	return true
+}
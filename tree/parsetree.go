@@ -76,6 +76,42 @@ func (p *PN) Child(cIdx int) parlex.ParseNode {
 	return p.C[cIdx]
 }
 
+// Leading returns the leading trivia of the node's lexeme if it has any. This
+// is part of the parlex.Trivia interface.
+func (p *PN) Leading() []parlex.Lexeme {
+	if t, ok := p.Lexeme.(parlex.Trivia); ok {
+		return t.Leading()
+	}
+	return nil
+}
+
+// Trailing returns the trailing trivia of the node's lexeme if it has any. This
+// is part of the parlex.Trivia interface.
+func (p *PN) Trailing() []parlex.Lexeme {
+	if t, ok := p.Lexeme.(parlex.Trivia); ok {
+		return t.Trailing()
+	}
+	return nil
+}
+
+// Source returns the source text of the node's lexeme if it is known. This is
+// part of the parlex.Trivia interface.
+func (p *PN) Source() (string, bool) {
+	if t, ok := p.Lexeme.(parlex.Trivia); ok {
+		return t.Source()
+	}
+	return "", false
+}
+
+// End returns the end position of the node's lexeme. If it is not known the
+// column will be 0. This is part of the parlex.Spanner interface.
+func (p *PN) End() (int, int) {
+	if l, c, ok := parlex.End(p.Lexeme); ok {
+		return l, c
+	}
+	return 0, 0
+}
+
 // String converts the entire tree (starting a *PN) to a string. This string can
 // be used to create a copy of the tree.
 func (p *PN) String() string {
@@ -153,14 +189,12 @@ func (p *PN) ChildAt(cIdx int, symbs ...string) bool {
 	return false
 }
 
-// Clone takes a node and clones it and all it's children.
+// Clone takes a node and clones it and all it's children. Positions and trivia
+// are preserved.
 func Clone(node parlex.ParseNode) *PN {
 	pn := &PN{
-		Lexeme: &lexeme.Lexeme{
-			K: node.Kind(),
-			V: node.Value(),
-		},
-		C: make([]*PN, node.Children()),
+		Lexeme: lexeme.Copy(node),
+		C:      make([]*PN, node.Children()),
 	}
 	for i := 0; i < node.Children(); i++ {
 		c := Clone(node.Child(i))
@@ -0,0 +1,36 @@
+// Package query provides CSS like selectors for finding nodes in a parse tree.
+// A query is compiled once and can be matched against any parlex.ParseNode.
+//
+// A selector is a list of compound selectors joined by combinators. A compound
+// selector starts with a kind, or * to match any kind, followed by any number
+// of filters and optionally a capture.
+//
+//	Object > KeyVal[value="\"id\""] @pair number @val
+//
+// Combinators
+//
+//	A B   B is a descendant of A
+//	A > B B is a child of A
+//	A + B B immediately follows it's sibling A
+//
+// Filters
+//
+//	[value="x"]  the node's value is "x"
+//	[kind="x"]   the node's kind is "x"
+//	[0="x"]      the value of the child at index 0 is "x", negative indexes
+//	             are relative to the end
+//
+// The operators are = (equal), != (not equal), ^= (prefix), $= (suffix) and ~=
+// (regular expression).
+//
+// Pseudo-classes
+//
+//	:first   the node is the first child of it's parent
+//	:last    the node is the last child of it's parent
+//	:nth(n)  the node is the child at index n, negative is relative to the end
+//	:empty   the node has no children
+//
+// A capture, @name, records the node that matched the compound selector.
+// Several selectors can be combined with commas. Whitespace is only allowed
+// between compound selectors and before a capture.
+package query
@@ -0,0 +1,365 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/grammar/regexgram"
+	"github.com/adamcolton/parlex/lexer/simplelexer"
+	"github.com/adamcolton/parlex/parser/packrat"
+	"github.com/adamcolton/parlex/tree"
+)
+
+const lexerRules = `
+  number /-?\d+/
+  ident  /[^\s\[\]\(\)>,+:@=!~^$"*]+/
+  string /"([^"\\]|(\\.))*"/
+  star   /\*/
+  gt     />/
+  plus   /\+/
+  comma  /,/
+  lb     /\[/
+  rb     /\]/
+  lp     /\(/
+  rp     /\)/
+  op     /[!~^$]?=/
+  colon  /:/
+  at     /@/
+  space  /\s+/
+`
+
+var lxr = parlex.MustLexer(simplelexer.New(lexerRules))
+
+const grammarRules = `
+  Query    -> Selector (Sep Selector)*
+  Sep      -> space? comma space?
+  Selector -> Compound (Comb Compound)*
+  Comb     -> space? gt space?
+           -> space? plus space?
+           -> space
+  Compound -> Kind Filter* Capture?
+  Kind     -> ident | string | star | number
+  Filter   -> lb Attr op string rb
+           -> colon Pseudo
+  Attr     -> ident | number
+  Pseudo   -> ident (lp number rp)?
+  Capture  -> space? at ident
+`
+
+var grmr, rdcr = regexgram.Must(grammarRules)
+var runner = parlex.New(lxr, packrat.New(grmr), rdcr)
+
+// Match is a node that matched a query along with any captures.
+type Match struct {
+	Node     parlex.ParseNode
+	Captures map[string]parlex.ParseNode
+}
+
+// Query is a compiled selector.
+type Query struct {
+	selectors []selector
+}
+
+type selector []step
+
+type step struct {
+	comb    byte
+	kind    string
+	filters []filter
+	capture string
+	anyKind bool
+}
+
+type filter func(node parlex.ParseNode, parent parlex.ParseNode, idx int) bool
+
+// Compile a query string.
+func Compile(str string) (*Query, error) {
+	root, err := runner.Run(strings.TrimSpace(str))
+	if err != nil {
+		return nil, err
+	}
+	q := &Query{}
+	for _, n := range root.(*tree.PN).C {
+		if n.Kind().String() != "Selector" {
+			continue
+		}
+		sel, err := compileSelector(n)
+		if err != nil {
+			return nil, err
+		}
+		q.selectors = append(q.selectors, sel)
+	}
+	return q, nil
+}
+
+// MustCompile calls Compile and panics if there is an error.
+func MustCompile(str string) *Query {
+	q, err := Compile(str)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+func compileSelector(n *tree.PN) (selector, error) {
+	var sel selector
+	comb := byte(0)
+	for _, c := range n.C {
+		switch c.Kind().String() {
+		case "Comb":
+			comb = ' '
+			for _, cc := range c.C {
+				switch cc.Kind().String() {
+				case "gt":
+					comb = '>'
+				case "plus":
+					comb = '+'
+				}
+			}
+		case "Compound":
+			s, err := compileCompound(c)
+			if err != nil {
+				return nil, err
+			}
+			s.comb = comb
+			sel = append(sel, s)
+		}
+	}
+	return sel, nil
+}
+
+func compileCompound(n *tree.PN) (step, error) {
+	var s step
+	for _, c := range n.C {
+		switch c.Kind().String() {
+		case "Kind":
+			k := c.C[0]
+			switch k.Kind().String() {
+			case "star":
+				s.anyKind = true
+			case "string":
+				s.kind, _ = strconv.Unquote(k.Value())
+			default:
+				s.kind = k.Value()
+			}
+		case "Filter":
+			f, err := compileFilter(c)
+			if err != nil {
+				return s, err
+			}
+			s.filters = append(s.filters, f)
+		case "Capture":
+			s.capture = c.C[len(c.C)-1].Value()
+		}
+	}
+	return s, nil
+}
+
+func compileFilter(n *tree.PN) (filter, error) {
+	if n.C[0].Kind().String() == "colon" {
+		return compilePseudo(n.C[1])
+	}
+	attr := n.C[1].C[0].Value()
+	val, err := strconv.Unquote(n.C[3].Value())
+	if err != nil {
+		return nil, err
+	}
+	cmp, err := compare(n.C[2].Value(), val)
+	if err != nil {
+		return nil, err
+	}
+	switch attr {
+	case "value":
+		return func(node, parent parlex.ParseNode, idx int) bool {
+			return cmp(node.Value())
+		}, nil
+	case "kind":
+		return func(node, parent parlex.ParseNode, idx int) bool {
+			return cmp(node.Kind().String())
+		}, nil
+	}
+	cIdx, err := strconv.Atoi(attr)
+	if err != nil {
+		return nil, fmt.Errorf("Unknown attribute: %s", attr)
+	}
+	return func(node, parent parlex.ParseNode, idx int) bool {
+		ch := child(node, cIdx)
+		return ch != nil && cmp(ch.Value())
+	}, nil
+}
+
+func compare(op, val string) (func(string) bool, error) {
+	switch op {
+	case "=":
+		return func(s string) bool { return s == val }, nil
+	case "!=":
+		return func(s string) bool { return s != val }, nil
+	case "^=":
+		return func(s string) bool { return strings.HasPrefix(s, val) }, nil
+	case "$=":
+		return func(s string) bool { return strings.HasSuffix(s, val) }, nil
+	case "~=":
+		re, err := regexp.Compile(val)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+	return nil, fmt.Errorf("Unknown operator: %s", op)
+}
+
+func compilePseudo(n *tree.PN) (filter, error) {
+	switch name := n.C[0].Value(); name {
+	case "first":
+		return func(node, parent parlex.ParseNode, idx int) bool {
+			return parent != nil && idx == 0
+		}, nil
+	case "last":
+		return func(node, parent parlex.ParseNode, idx int) bool {
+			return parent != nil && idx == parent.Children()-1
+		}, nil
+	case "empty":
+		return func(node, parent parlex.ParseNode, idx int) bool {
+			return node.Children() == 0
+		}, nil
+	case "nth":
+		if len(n.C) < 3 {
+			return nil, fmt.Errorf("nth requires an index")
+		}
+		nth, _ := strconv.Atoi(n.C[2].Value())
+		return func(node, parent parlex.ParseNode, idx int) bool {
+			if parent == nil {
+				return false
+			}
+			i := nth
+			if i < 0 {
+				i += parent.Children()
+			}
+			return idx == i
+		}, nil
+	default:
+		return nil, fmt.Errorf("Unknown pseudo-class: %s", name)
+	}
+}
+
+func child(node parlex.ParseNode, cIdx int) parlex.ParseNode {
+	ln := node.Children()
+	if cIdx < 0 {
+		cIdx += ln
+	}
+	if cIdx < 0 || cIdx >= ln {
+		return nil
+	}
+	return node.Child(cIdx)
+}
+
+// frame is one node in the path from the root to the node being checked.
+type frame struct {
+	node parlex.ParseNode
+	idx  int
+}
+
+// Match returns every node in the tree that matches the query in depth first
+// order.
+func (q *Query) Match(root parlex.ParseNode) []Match {
+	if root == nil {
+		return nil
+	}
+	var ms []Match
+	q.walk([]frame{{root, -1}}, &ms)
+	return ms
+}
+
+// All returns every node in the tree that matches the query.
+func (q *Query) All(root parlex.ParseNode) []parlex.ParseNode {
+	ms := q.Match(root)
+	out := make([]parlex.ParseNode, len(ms))
+	for i, m := range ms {
+		out[i] = m.Node
+	}
+	return out
+}
+
+// First returns the first node that matches the query or nil if none do.
+func (q *Query) First(root parlex.ParseNode) parlex.ParseNode {
+	if ms := q.Match(root); len(ms) > 0 {
+		return ms[0].Node
+	}
+	return nil
+}
+
+func (q *Query) walk(path []frame, ms *[]Match) {
+	for _, sel := range q.selectors {
+		caps := make(map[string]parlex.ParseNode)
+		if sel.match(path, len(path)-1, len(sel)-1, caps) {
+			*ms = append(*ms, Match{
+				Node:     path[len(path)-1].node,
+				Captures: caps,
+			})
+			break
+		}
+	}
+	node := path[len(path)-1].node
+	for i := 0; i < node.Children(); i++ {
+		q.walk(append(path, frame{node.Child(i), i}), ms)
+	}
+}
+
+// match checks if step s of the selector matches the node at position p in the
+// path, then checks the steps before it.
+func (sel selector) match(path []frame, p, s int, caps map[string]parlex.ParseNode) bool {
+	st := sel[s]
+	if !st.matches(path, p) || !sel.matchPrev(path, p, s, caps) {
+		return false
+	}
+	if st.capture != "" {
+		caps[st.capture] = path[p].node
+	}
+	return true
+}
+
+// matchPrev checks the steps before s, relative to position p, using the
+// combinator of step s.
+func (sel selector) matchPrev(path []frame, p, s int, caps map[string]parlex.ParseNode) bool {
+	if s == 0 {
+		return true
+	}
+	switch sel[s].comb {
+	case '>':
+		return p > 0 && sel.match(path, p-1, s-1, caps)
+	case '+':
+		if p == 0 || path[p].idx < 1 {
+			return false
+		}
+		parent := path[p-1].node
+		sibPath := append(append([]frame(nil), path[:p]...), frame{parent.Child(path[p].idx - 1), path[p].idx - 1})
+		return sel.match(sibPath, p, s-1, caps)
+	default:
+		for a := p - 1; a >= 0; a-- {
+			if sel.match(path, a, s-1, caps) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (st step) matches(path []frame, p int) bool {
+	f := path[p]
+	if !st.anyKind && f.node.Kind().String() != st.kind {
+		return false
+	}
+	var parent parlex.ParseNode
+	if p > 0 {
+		parent = path[p-1].node
+	}
+	for _, fl := range st.filters {
+		if !fl(f.node, parent, f.idx) {
+			return false
+		}
+	}
+	return true
+}
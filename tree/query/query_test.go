@@ -0,0 +1,119 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/tree"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTree = `
+  Object {
+    KeyVal: "id" {
+      number: "1"
+    }
+    KeyVal: "tags" {
+      Array {
+        string: "a"
+        string: "b"
+        number: "3"
+      }
+    }
+    KeyVal: "empty" {
+      Object
+    }
+  }
+`
+
+func values(ns []parlex.ParseNode) []string {
+	out := make([]string, len(ns))
+	for i, n := range ns {
+		out[i] = n.Value()
+	}
+	return out
+}
+
+func TestQuery(t *testing.T) {
+	root, err := tree.New(testTree)
+	assert.NoError(t, err)
+
+	tt := map[string][]string{
+		`KeyVal`:                           {"id", "tags", "empty"},
+		`Object > KeyVal`:                  {"id", "tags", "empty"},
+		`Object number`:                    {"1", "3"},
+		`KeyVal > number`:                  {"1"},
+		`Array > *`:                        {"a", "b", "3"},
+		`string + *`:                       {"b", "3"},
+		`string + number`:                  {"3"},
+		`KeyVal[value="tags"] *`:           {"", "a", "b", "3"},
+		`KeyVal[value^="t"]`:               {"tags"},
+		`KeyVal[value$="y"]`:               {"empty"},
+		`KeyVal[value~="^i|^e"]`:           {"id", "empty"},
+		`KeyVal[value!="id"]`:              {"tags", "empty"},
+		`KeyVal[0="1"]`:                    {"id"},
+		`*[kind="string"]`:                 {"a", "b"},
+		`Array > *:first`:                  {"a"},
+		`Array > *:last`:                   {"3"},
+		`Array > *:nth(1)`:                 {"b"},
+		`Array > *:nth(-2)`:                {"b"},
+		`KeyVal > *:empty`:                 {"1", ""},
+		`Object:empty`:                     {""},
+		`number, string`:                   {"1", "a", "b", "3"},
+		`KeyVal > number , Array > *:last`: {"1", "3"},
+		`Object > KeyVal Array`:            {""},
+		`Object Object`:                    {""},
+		`Array number`:                     {"3"},
+		`Array > string + string`:          {"b"},
+		`number + string`:                  {},
+		`Missing`:                          {},
+	}
+
+	for q, expected := range tt {
+		t.Run(q, func(t *testing.T) {
+			got := MustCompile(q).All(root)
+			assert.Equal(t, expected, values(got))
+		})
+	}
+}
+
+func TestCaptures(t *testing.T) {
+	root, err := tree.New(testTree)
+	assert.NoError(t, err)
+
+	ms := MustCompile(`KeyVal @key > Array > *:last @last`).Match(root)
+	if assert.Len(t, ms, 1) {
+		assert.Equal(t, "3", ms[0].Node.Value())
+		assert.Equal(t, "tags", ms[0].Captures["key"].Value())
+		assert.Equal(t, ms[0].Node, ms[0].Captures["last"])
+	}
+
+	// A failed branch should not leave captures behind
+	ms = MustCompile(`KeyVal[value="id"] @key number @n`).Match(root)
+	if assert.Len(t, ms, 1) {
+		assert.Equal(t, "id", ms[0].Captures["key"].Value())
+		assert.Equal(t, "1", ms[0].Captures["n"].Value())
+	}
+}
+
+func TestFirst(t *testing.T) {
+	root, err := tree.New(testTree)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "a", MustCompile(`string`).First(root).Value())
+	assert.Nil(t, MustCompile(`Missing`).First(root))
+	assert.Nil(t, MustCompile(`string`).First(nil))
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, q := range []string{
+		`KeyVal[foo="x"]`,
+		`KeyVal:bar`,
+		`KeyVal[value~="("]`,
+		`KeyVal >`,
+		`[value="x"]`,
+	} {
+		_, err := Compile(q)
+		assert.Error(t, err, q)
+	}
+}
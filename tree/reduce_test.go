@@ -3,6 +3,8 @@ package tree
 import (
 	"testing"
 
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/lexeme"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -243,3 +245,117 @@ func TestRemoveAll(t *testing.T) {
 	pn = r.Reduce(pn).(*PN)
 	assert.Len(t, pn.C[0].C, 1)
 }
+
+func spanOf(p *PN) [4]int {
+	l, c := p.Pos()
+	el, ec, _ := parlex.End(p.Lexeme)
+	return [4]int{l, c, el, ec}
+}
+
+func TestSpans(t *testing.T) {
+	// E { ( int ) } with positions from "(12)"
+	build := func() *PN {
+		return &PN{
+			Lexeme: lexeme.String("E").At(0, 1).To(0, 5),
+			C: []*PN{
+				{Lexeme: lexeme.String("(").Set("(").At(0, 1).To(0, 2)},
+				{Lexeme: lexeme.String("int").Set("12").At(0, 2).To(0, 4)},
+				{Lexeme: lexeme.String(")").Set(")").At(0, 4).To(0, 5)},
+			},
+		}
+	}
+
+	pn := build()
+	cp := Clone(pn)
+	assert.Equal(t, [4]int{0, 1, 0, 5}, spanOf(cp))
+	assert.Equal(t, [4]int{0, 2, 0, 4}, spanOf(cp.C[1]))
+
+	cp = Reducer{}.RawReduce(pn)
+	assert.Equal(t, [4]int{0, 2, 0, 4}, spanOf(cp.C[1]))
+
+	pn = build()
+	pn.PromoteChildValue(1)
+	assert.Equal(t, "12", pn.Value())
+	assert.Equal(t, "E", pn.Kind().String())
+	assert.Equal(t, [4]int{0, 1, 0, 5}, spanOf(pn))
+
+	pn = build()
+	pn.ReplaceWithChild(1)
+	assert.Equal(t, "int", pn.Kind().String())
+	assert.Equal(t, [4]int{0, 1, 0, 5}, spanOf(pn))
+
+	pn = build()
+	pn.PromoteChild(0)
+	assert.Equal(t, "(", pn.Kind().String())
+	assert.Equal(t, [4]int{0, 1, 0, 5}, spanOf(pn))
+
+	// a node without a position takes the span of the child
+	pn = build()
+	pn.Lexeme = lexeme.String("E")
+	pn.PromoteChildValue(1)
+	assert.Equal(t, [4]int{0, 2, 0, 4}, spanOf(pn))
+}
+
+func TestNewReductions(t *testing.T) {
+	pn := &PN{
+		Lexeme: lexeme.String("Name"),
+		C: []*PN{
+			{Lexeme: lexeme.String("id").Set("a").At(0, 1).To(0, 2)},
+			{Lexeme: lexeme.String("id").Set("b").At(0, 3).To(0, 4)},
+		},
+	}
+	assert.False(t, pn.SwapChildren(0, 2))
+	assert.True(t, pn.SwapChildren(0, -1))
+	assert.Equal(t, "b", pn.C[0].Value())
+
+	pn.Wrap("Outer")
+	assert.Equal(t, "Outer", pn.Kind().String())
+	assert.Equal(t, pn, pn.C[0].P)
+	assert.Equal(t, pn.C[0], pn.C[0].C[0].P)
+
+	inner := pn.C[0]
+	inner.ConcatChildValues("-")
+	assert.Equal(t, "b-a", inner.Value())
+	assert.Len(t, inner.C, 0)
+	assert.Equal(t, [4]int{0, 1, 0, 4}, spanOf(inner))
+
+	inner.Rename("Renamed")
+	assert.Equal(t, "Renamed", inner.Kind().String())
+	assert.Equal(t, "b-a", inner.Value())
+	assert.Equal(t, [4]int{0, 1, 0, 4}, spanOf(inner))
+}
+
+func TestCollapse(t *testing.T) {
+	build := func() *PN {
+		pn := &PN{
+			Lexeme: lexeme.String("Version"),
+			C: []*PN{
+				{Lexeme: lexeme.String("digit").Set("1").At(0, 1).To(0, 2)},
+				{Lexeme: lexeme.String("digit").Set("0").At(0, 2).To(0, 3)},
+				{Lexeme: lexeme.String("dot").Set(".").At(0, 3).To(0, 4)},
+				{Lexeme: lexeme.String("dot").Set(".").At(0, 4).To(0, 5)},
+				{Lexeme: lexeme.String("digit").Set("2").At(0, 5).To(0, 6)},
+			},
+		}
+		for _, c := range pn.C {
+			c.P = pn
+		}
+		return pn
+	}
+
+	pn := build()
+	pn.Collapse()
+	if assert.Len(t, pn.C, 3) {
+		assert.Equal(t, "10", pn.C[0].Value())
+		assert.Equal(t, [4]int{0, 1, 0, 3}, spanOf(pn.C[0]))
+		assert.Equal(t, "..", pn.C[1].Value())
+		assert.Equal(t, "2", pn.C[2].Value())
+	}
+
+	pn = build()
+	Collapse("digit")(pn)
+	if assert.Len(t, pn.C, 4) {
+		assert.Equal(t, "10", pn.C[0].Value())
+		assert.Equal(t, ".", pn.C[1].Value())
+	}
+}
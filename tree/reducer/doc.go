@@ -1,2 +1,22 @@
 // Package reducer parses a string description into a tree reducer.
+//
+// Each rule is a kind followed by a chain of reductions joined by periods.
+//   KeyVal PromoteChildValue(0).RemoveChild(0)
+//   Name   ConcatChildValues(".")
+//   Expr   If(And(ChildCountIs(3), ChildIs(1, "op")), PromoteChildValue(1), Nil)
+//
+// Reductions
+//   PromoteSingleChild() PromoteGrandChildren() PromoteChild(i)
+//   PromoteChildrenOf(i) PromoteChildValue(i) ReplaceWithChild(i)
+//   RemoveChild(i) RemoveChildren(i, ...) RemoveAll("kind", ...)
+//   Rename("kind") SetValue("value") ConcatChildValues("sep")
+//   Collapse("kind", ...) Flatten("kind") Wrap("kind") SwapChildren(i, j)
+//   If(condition, reductions, reductions) Nil
+//
+// Conditions
+//   ChildIs(i, "kind") ChildCountIs(n) ValueMatches("regexp")
+//   And(condition, ...) Or(condition, ...) Not(condition)
+//
+// Go reductions can be called by name, with no arguments, by passing them to
+// ParseWith.
 package reducer
@@ -1,6 +1,8 @@
 package reducer
 
 import (
+	"fmt"
+	"regexp"
 	"strconv"
 
 	"github.com/adamcolton/parlex"
@@ -22,6 +24,18 @@ const lexerRules = `
   RemoveChildren
   PromoteSingleChild
   ReplaceWithChild
+  Rename
+  SetValue
+  ConcatChildValues
+  Collapse
+  Flatten
+  Wrap
+  SwapChildren
+  ChildCountIs
+  ValueMatches
+  And
+  Or
+  Not
   Nil
   number  /-?\d*\.?\d+/
   rule    /(\w+)/
@@ -39,7 +53,7 @@ var lxr = parlex.MustLexer(simplelexer.New(lexerRules))
 const grammarRules = `
   Rules        -> Rule*
   Rule         -> rule Chain
-  Chain        -> (Reduction period)* Reduction
+  Chain        -> SepBy(Reduction, period)
   Reduction    -> PromoteSingleChild NoArgs
                -> RemoveChildren VarNumArg
                -> PromoteChildValue OneNumArg
@@ -50,13 +64,30 @@ const grammarRules = `
                -> PromoteChildrenOf OneNumArg
                -> PromoteChild OneNumArg
                -> RemoveAll VarStrArg
+               -> Rename OneStrArg
+               -> SetValue OneStrArg
+               -> ConcatChildValues OptStrArg
+               -> Collapse VarStrArg
+               -> Collapse NoArgs
+               -> Flatten OneStrArg
+               -> Wrap OneStrArg
+               -> SwapChildren TwoNumArg
                -> Nil
                -> If lp Condition comma Chain comma Chain rp
-  VarNumArg    -> lp (number comma)* number rp
-  VarStrArg    -> lp (string comma)* string rp
+               -> rule NoArgs
+  VarNumArg    -> lp SepBy(number, comma) rp
+  VarStrArg    -> lp SepBy(string, comma) rp
   OneNumArg    -> lp number rp
+  TwoNumArg    -> lp number comma number rp
+  OneStrArg    -> lp string rp
+  OptStrArg    -> lp string? rp
   NoArgs       -> lp rp
   Condition    -> ChildIs lp number comma string rp
+               -> ChildCountIs lp number rp
+               -> ValueMatches lp string rp
+               -> And lp SepBy(Condition, comma) rp
+               -> Or lp SepBy(Condition, comma) rp
+               -> Not lp Condition rp
 `
 
 var grmr, grmrRdcr = regexgram.Must(grammarRules)
@@ -68,22 +99,44 @@ var rdcr = tree.Merge(grmrRdcr, tree.Reducer{
 	"VarNumArg": tree.RemoveChildren(0, -1).RemoveAll("comma"),
 	"VarStrArg": tree.RemoveChildren(0, -1).RemoveAll("comma"),
 	"OneNumArg": tree.RemoveChildren(0, -1),
+	"TwoNumArg": tree.RemoveChildren(0, -1).RemoveAll("comma"),
+	"OneStrArg": tree.RemoveChildren(0, -1),
+	"OptStrArg": tree.RemoveChildren(0, -1),
 	"Condition": tree.RemoveAll("comma", "lp", "rp").PromoteChild(0),
 })
 
 var runner = parlex.New(lxr, prsr, rdcr)
 
+// ErrUnknownFunc is returned when a reducer string calls a function that was
+// not provided.
+type ErrUnknownFunc string
+
+func (e ErrUnknownFunc) Error() string {
+	return fmt.Sprintf("Unknown reduction func: %s", string(e))
+}
+
 // Parse a reducer string.
 func Parse(str string) (tree.Reducer, error) {
+	return ParseWith(str, nil)
+}
+
+// ParseWith parses a reducer string that can call the named reductions in
+// funcs. A func is called with no arguments in the reducer string.
+//   Name Normalize().PromoteSingleChild()
+func ParseWith(str string, funcs map[string]tree.Reduction) (tree.Reducer, error) {
 	root, err := runner.Run(str)
 	if err != nil {
 		return nil, err
 	}
+	ev := evaluator(funcs)
 	rdcr := make(tree.Reducer)
 	for _, n := range root.(*tree.PN).C {
 		if n.Kind().String() == "Rule" {
-			k, v := evalRule(n)
-			rdcr[k] = v
+			r, err := ev.reduction(n.C...)
+			if err != nil {
+				return nil, err
+			}
+			rdcr[n.Value()] = r
 		}
 	}
 	return rdcr, nil
@@ -98,11 +151,18 @@ func Must(str string) tree.Reducer {
 	return rt
 }
 
-func evalRule(n *tree.PN) (string, tree.Reduction) {
-	return n.Value(), evalReduction(n.C...)
+// MustWith calls ParseWith and panics if there is an error.
+func MustWith(str string, funcs map[string]tree.Reduction) tree.Reducer {
+	rt, err := ParseWith(str, funcs)
+	if err != nil {
+		panic(err)
+	}
+	return rt
 }
 
-func evalReduction(ns ...*tree.PN) tree.Reduction {
+type evaluator map[string]tree.Reduction
+
+func (ev evaluator) reduction(ns ...*tree.PN) (tree.Reduction, error) {
 	var r tree.Reduction
 	for _, n := range ns {
 		switch n.Kind().String() {
@@ -124,14 +184,52 @@ func evalReduction(ns ...*tree.PN) tree.Reduction {
 			r = r.RemoveAll(evalVarStrArgs(n.C[0])...)
 		case "PromoteChild":
 			r = r.PromoteChild(evalOneNumArg(n.C[0]))
+		case "Rename":
+			r = r.Rename(evalOneStrArg(n.C[0].C[0]))
+		case "SetValue":
+			r = r.SetValue(evalOneStrArg(n.C[0].C[0]))
+		case "ConcatChildValues":
+			sep := ""
+			if len(n.C[0].C) > 0 {
+				sep = evalOneStrArg(n.C[0].C[0])
+			}
+			r = r.ConcatChildValues(sep)
+		case "Collapse":
+			var kinds []string
+			if n.C[0].Kind().String() == "VarStrArg" {
+				kinds = evalVarStrArgs(n.C[0])
+			}
+			r = r.Collapse(kinds...)
+		case "Flatten":
+			r = r.Flatten(evalOneStrArg(n.C[0].C[0]))
+		case "Wrap":
+			r = r.Wrap(evalOneStrArg(n.C[0].C[0]))
+		case "SwapChildren":
+			args := evalVarNumArgs(n.C[0])
+			r = r.SwapChildren(args[0], args[1])
+		case "rule":
+			fn, ok := ev[n.Value()]
+			if !ok {
+				return nil, ErrUnknownFunc(n.Value())
+			}
+			r = tree.Chain(r, fn)
 		case "If":
-			c := evalConditional(n.C[0])
-			t := evalReduction(n.C[1].C...)
-			e := evalReduction(n.C[2].C...)
+			c, err := evalConditional(n.C[0])
+			if err != nil {
+				return nil, err
+			}
+			t, err := ev.reduction(n.C[1].C...)
+			if err != nil {
+				return nil, err
+			}
+			e, err := ev.reduction(n.C[2].C...)
+			if err != nil {
+				return nil, err
+			}
 			r = r.If(c, t, e)
 		}
 	}
-	return r
+	return r, nil
 }
 
 func evalVarNumArgs(n *tree.PN) []int {
@@ -165,11 +263,39 @@ func evalOneStrArg(n *tree.PN) string {
 	return v[1 : len(v)-1]
 }
 
-func evalConditional(n *tree.PN) tree.Condition {
+func evalConditional(n *tree.PN) (tree.Condition, error) {
 	switch n.Kind().String() {
 	case "ChildIs":
 		i, _ := strconv.Atoi(n.C[0].Value())
-		return tree.ChildIs(i, evalOneStrArg(n.C[1]))
+		return tree.ChildIs(i, evalOneStrArg(n.C[1])), nil
+	case "ChildCountIs":
+		i, _ := strconv.Atoi(n.C[0].Value())
+		return tree.ChildCountIs(i), nil
+	case "ValueMatches":
+		re, err := regexp.Compile(evalOneStrArg(n.C[0]))
+		if err != nil {
+			return nil, err
+		}
+		return tree.ValueMatches(re), nil
+	case "Not":
+		c, err := evalConditional(n.C[0])
+		if err != nil {
+			return nil, err
+		}
+		return tree.Not(c), nil
+	case "And", "Or":
+		cs := make([]tree.Condition, len(n.C))
+		for i, c := range n.C {
+			var err error
+			cs[i], err = evalConditional(c)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if n.Kind().String() == "And" {
+			return tree.And(cs...), nil
+		}
+		return tree.Or(cs...), nil
 	}
-	return nil
+	return nil, nil
 }
@@ -1,6 +1,7 @@
 package reducer
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/adamcolton/parlex/tree"
@@ -570,3 +571,170 @@ func TestChildIs(t *testing.T) {
 `
 	assert.Equal(t, expected, got)
 }
+
+func TestRenameSetValue(t *testing.T) {
+	rdcr := Must(`
+		A Rename("X")
+		B SetValue("set").Rename("Y")
+	`)
+	pn, err := tree.New(`
+		Root {
+			A: "a"
+			B: "b"
+		}
+	`)
+	assert.NoError(t, err)
+	got := rdcr.Reduce(pn).(*tree.PN).String()
+	assert.Equal(t, "Root {\n\tX: \"a\"\n\tY: \"set\"\n}\n", got)
+}
+
+func TestConcatFlattenWrapSwap(t *testing.T) {
+	rdcr := Must(`
+		Name   ConcatChildValues(".")
+		Digits ConcatChildValues()
+		Num    Collapse()
+		Ver    Collapse("d")
+		List   Flatten("List")
+		Pair   SwapChildren(0, -1).Wrap("Group")
+	`)
+	pn, err := tree.New(`
+		Root {
+			Name {
+				id: "a"
+				id: "b"
+			}
+			Digits {
+				d: "1"
+				d: "2"
+			}
+			Num {
+				d: "1"
+				d: "2"
+			}
+			Ver {
+				d: "1"
+				p: "."
+				p: "."
+				d: "2"
+				d: "3"
+			}
+			List {
+				x: "1"
+				List {
+					x: "2"
+					List {
+						x: "3"
+					}
+				}
+			}
+			Pair {
+				k: "key"
+				v: "val"
+			}
+		}
+	`)
+	assert.NoError(t, err)
+	got := rdcr.Reduce(pn).(*tree.PN).String()
+	expected := `Root {
+	Name: "a.b"
+	Digits: "12"
+	Num {
+		d: "12"
+	}
+	Ver {
+		d: "1"
+		p: "."
+		p: "."
+		d: "23"
+	}
+	List {
+		x: "1"
+		x: "2"
+		x: "3"
+	}
+	Group {
+		Pair {
+			v: "val"
+			k: "key"
+		}
+	}
+}
+`
+	assert.Equal(t, expected, got)
+}
+
+func TestConditions(t *testing.T) {
+	rdcr := Must(`
+		E If(And(ChildCountIs(1), Not(ValueMatches("^skip"))), PromoteSingleChild(), Nil)
+		F If(Or(ChildIs(0, "a"), ChildIs(0, "b")), Rename("AB"), Rename("Other"))
+	`)
+	pn, err := tree.New(`
+		Root {
+			E {
+				n: "1"
+			}
+			E: "skip" {
+				n: "2"
+			}
+			E {
+				n: "3"
+				n: "4"
+			}
+			F {
+				b
+			}
+			F {
+				c
+			}
+		}
+	`)
+	assert.NoError(t, err)
+	got := rdcr.Reduce(pn).(*tree.PN).String()
+	expected := `Root {
+	n: "1"
+	E: "skip" {
+		n: "2"
+	}
+	E {
+		n: "3"
+		n: "4"
+	}
+	AB {
+		b
+	}
+	Other {
+		c
+	}
+}
+`
+	assert.Equal(t, expected, got)
+}
+
+func TestParseWith(t *testing.T) {
+	upper := func(node *tree.PN) {
+		node.SetValue(strings.ToUpper(node.Value()))
+	}
+	rdcr, err := ParseWith(`
+		A Upper().Rename("B")
+		C If(ChildCountIs(0), Upper(), Nil)
+	`, map[string]tree.Reduction{
+		"Upper": upper,
+	})
+	assert.NoError(t, err)
+	pn, err := tree.New(`
+		Root {
+			A: "a"
+			C: "c"
+		}
+	`)
+	assert.NoError(t, err)
+	got := rdcr.Reduce(pn).(*tree.PN).String()
+	assert.Equal(t, "Root {\n\tB: \"A\"\n\tC: \"C\"\n}\n", got)
+
+	_, err = ParseWith(`A Lower()`, map[string]tree.Reduction{"Upper": upper})
+	assert.Equal(t, ErrUnknownFunc("Lower"), err)
+	assert.Equal(t, "Unknown reduction func: Lower", err.Error())
+
+	_, err = Parse(`A If(ValueMatches("("), Nil, Nil)`)
+	assert.Error(t, err)
+}
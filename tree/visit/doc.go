@@ -0,0 +1,7 @@
+// Package visit provides traversals over parse trees that complement
+// tree.Reducer. Walk and Apply call enter and exit hooks with a Cursor that
+// gives the parent context of a node and allows children to be skipped or the
+// node to be replaced or removed. Fold evaluates a tree to any value. A
+// Rewriter applies rules, often built with Pattern, until the tree stops
+// changing.
+package visit
@@ -0,0 +1,162 @@
+package visit
+
+import (
+	"errors"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/tree"
+)
+
+// ErrNoFixpoint is returned by Rewrite if the rules are still changing the
+// tree after the maximum number of passes.
+var ErrNoFixpoint = errors.New("Rewrite did not reach a fixpoint")
+
+// DefaultMaxPasses is used by a Rewriter if MaxPasses is not set.
+const DefaultMaxPasses = 100
+
+// Rule rewrites any node that matches it's condition.
+type Rule struct {
+	Match   tree.Condition
+	Rewrite tree.Reduction
+}
+
+// Rewriter applies rules to a tree until it stops changing.
+type Rewriter struct {
+	Rules     []Rule
+	MaxPasses int
+}
+
+// Add a rule to the Rewriter.
+func (r *Rewriter) Add(match tree.Condition, rewrite tree.Reduction) *Rewriter {
+	r.Rules = append(r.Rules, Rule{
+		Match:   match,
+		Rewrite: rewrite,
+	})
+	return r
+}
+
+// Rewrite applies the rules bottom up to every node in the tree, for each node
+// only the first rule that matches is applied. Passes are repeated until one
+// makes no change to the tree. A rule changes a node by changing it's kind or
+// value or by replacing, adding or removing it's children; changes made further
+// down the tree are not seen. If node is a *tree.PN it is modified in place,
+// otherwise it is cloned first.
+func (r *Rewriter) Rewrite(node parlex.ParseNode) (*tree.PN, error) {
+	if node == nil {
+		return nil, nil
+	}
+	pn, ok := node.(*tree.PN)
+	if !ok {
+		pn = tree.Clone(node)
+	}
+	max := r.MaxPasses
+	if max <= 0 {
+		max = DefaultMaxPasses
+	}
+	for i := 0; i < max; i++ {
+		if !r.pass(pn) {
+			return pn, nil
+		}
+	}
+	return pn, ErrNoFixpoint
+}
+
+func (r *Rewriter) pass(pn *tree.PN) bool {
+	changed := false
+	for _, c := range pn.C {
+		if r.pass(c) {
+			changed = true
+		}
+	}
+	for _, rule := range r.Rules {
+		if rule.Match != nil && !rule.Match(pn) {
+			continue
+		}
+		before := snapshot(pn)
+		rule.Rewrite(pn)
+		for _, c := range pn.C {
+			c.P = pn
+		}
+		if before.changed(pn) {
+			changed = true
+		}
+		break
+	}
+	return changed
+}
+
+// nodeState holds enough of a node to tell if a rule changed it.
+type nodeState struct {
+	kind, value string
+	children    []*tree.PN
+}
+
+func snapshot(pn *tree.PN) nodeState {
+	return nodeState{
+		kind:     pn.Kind().String(),
+		value:    pn.Value(),
+		children: append([]*tree.PN(nil), pn.C...),
+	}
+}
+
+func (s nodeState) changed(pn *tree.PN) bool {
+	if s.kind != pn.Kind().String() || s.value != pn.Value() || len(s.children) != len(pn.C) {
+		return true
+	}
+	for i, c := range pn.C {
+		if s.children[i] != c {
+			return true
+		}
+	}
+	return false
+}
+
+// Wildcard is the kind that matches any node in a Pattern.
+const Wildcard = "_"
+
+// Pattern returns a Condition from a tree definition in the format used by
+// tree.New. A node in the pattern matches a node with the same kind, or any
+// kind if the pattern kind is the Wildcard. If the pattern node has a value,
+// the values must also match. If the pattern node has children, the node must
+// have the same number of children and each must match; a pattern node with
+// no children matches a node with any children.
+func Pattern(str string) (tree.Condition, error) {
+	p, err := tree.New(str)
+	if err != nil {
+		return nil, err
+	}
+	return func(node *tree.PN) bool {
+		return matchPattern(p, node)
+	}, nil
+}
+
+// MustPattern calls Pattern and panics if there is an error.
+func MustPattern(str string) tree.Condition {
+	c, err := Pattern(str)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func matchPattern(p parlex.ParseNode, node parlex.ParseNode) bool {
+	if k := p.Kind().String(); k != Wildcard && k != node.Kind().String() {
+		return false
+	}
+	if v := p.Value(); v != "" && v != node.Value() {
+		return false
+	}
+	ln := p.Children()
+	if ln == 0 {
+		return true
+	}
+	if ln != node.Children() {
+		return false
+	}
+	for i := 0; i < ln; i++ {
+		if !matchPattern(p.Child(i), node.Child(i)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+package visit
+
+import (
+	"errors"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/tree"
+)
+
+// ErrReadOnly is the panic value if Replace or Remove is called on a Cursor
+// during Walk.
+var ErrReadOnly = errors.New("Cannot modify tree during Walk, use Apply")
+
+// Cursor describes the position of a node during a traversal. The chain of
+// parent cursors is available even when the nodes do not implement Parent.
+type Cursor struct {
+	node     parlex.ParseNode
+	parent   *Cursor
+	idx      int
+	depth    int
+	readOnly bool
+	skip     bool
+	removed  bool
+	replaced bool
+}
+
+// Node being visited.
+func (c *Cursor) Node() parlex.ParseNode { return c.node }
+
+// PN returns the node being visited as a *tree.PN. During Walk it will be nil
+// unless the tree is made of *tree.PN.
+func (c *Cursor) PN() *tree.PN {
+	pn, _ := c.node.(*tree.PN)
+	return pn
+}
+
+// Parent returns the cursor of the parent node, it will be nil for the root.
+func (c *Cursor) Parent() *Cursor { return c.parent }
+
+// Index of the node in it's parent. The root has an index of -1.
+func (c *Cursor) Index() int { return c.idx }
+
+// Depth of the node. The root has a depth of 0.
+func (c *Cursor) Depth() int { return c.depth }
+
+// SkipChildren prevents the children of the node from being visited. It only
+// has an effect when called from Enter.
+func (c *Cursor) SkipChildren() { c.skip = true }
+
+// Replace the node being visited. If called from Enter, the children of the
+// replacement are visited. Replacing the root changes the value returned from
+// Apply.
+func (c *Cursor) Replace(node *tree.PN) {
+	if c.readOnly {
+		panic(ErrReadOnly)
+	}
+	c.node = node
+	c.replaced = true
+	c.removed = false
+}
+
+// Remove the node being visited from it's parent. If called from Enter, the
+// children are not visited and Exit is not called. Removing the root causes
+// Apply to return nil.
+func (c *Cursor) Remove() {
+	if c.readOnly {
+		panic(ErrReadOnly)
+	}
+	c.removed = true
+	c.skip = true
+}
+
+// Visitor holds the hooks called during a traversal. Enter is called before
+// the children are visited (pre-order) and Exit is called after (post-order).
+// Either can be nil.
+type Visitor struct {
+	Enter func(c *Cursor)
+	Exit  func(c *Cursor)
+}
+
+// Walk traverses any parlex.ParseNode tree depth first. The tree cannot be
+// modified; Replace or Remove will panic.
+func Walk(node parlex.ParseNode, v Visitor) {
+	if node == nil {
+		return
+	}
+	v.visit(&Cursor{
+		node:     node,
+		idx:      -1,
+		readOnly: true,
+	})
+}
+
+// Apply traverses the tree depth first and allows nodes to be replaced or
+// removed. If node is a *tree.PN it is modified in place, otherwise it is
+// cloned first. The root, which may have been replaced, is returned.
+func Apply(node parlex.ParseNode, v Visitor) *tree.PN {
+	if node == nil {
+		return nil
+	}
+	pn, ok := node.(*tree.PN)
+	if !ok {
+		pn = tree.Clone(node)
+	}
+	c := &Cursor{
+		node: pn,
+		idx:  -1,
+	}
+	v.visit(c)
+	if c.removed {
+		return nil
+	}
+	pn = c.PN()
+	pn.P = nil
+	return pn
+}
+
+func (v Visitor) visit(c *Cursor) {
+	if v.Enter != nil {
+		v.Enter(c)
+	}
+	if !c.skip {
+		if c.readOnly {
+			v.walkChildren(c)
+		} else {
+			v.applyChildren(c)
+		}
+	}
+	if v.Exit != nil && !c.removed {
+		v.Exit(c)
+	}
+	if c.replaced && !c.removed {
+		pn := c.PN()
+		for _, ch := range pn.C {
+			ch.P = pn
+		}
+	}
+}
+
+func (v Visitor) walkChildren(c *Cursor) {
+	for i := 0; i < c.node.Children(); i++ {
+		v.visit(&Cursor{
+			node:     c.node.Child(i),
+			parent:   c,
+			idx:      i,
+			depth:    c.depth + 1,
+			readOnly: true,
+		})
+	}
+}
+
+func (v Visitor) applyChildren(c *Cursor) {
+	pn := c.PN()
+	cs := make([]*tree.PN, 0, len(pn.C))
+	for _, ch := range pn.C {
+		cc := &Cursor{
+			node:   ch,
+			parent: c,
+			idx:    len(cs),
+			depth:  c.depth + 1,
+		}
+		v.visit(cc)
+		if cc.removed {
+			continue
+		}
+		ch = cc.PN()
+		ch.P = pn
+		cs = append(cs, ch)
+	}
+	pn.C = cs
+}
+
+// Fold reduces a tree to a single value. The children of a node are folded
+// first and their results are passed to fn along with the node, which allows
+// a tree to be evaluated to a type other than parlex.ParseNode.
+func Fold(node parlex.ParseNode, fn func(node parlex.ParseNode, children []interface{}) interface{}) interface{} {
+	if node == nil {
+		return nil
+	}
+	cs := make([]interface{}, node.Children())
+	for i := range cs {
+		cs[i] = Fold(node.Child(i), fn)
+	}
+	return fn(node, cs)
+}
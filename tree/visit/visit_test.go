@@ -0,0 +1,235 @@
+package visit
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/adamcolton/parlex"
+	"github.com/adamcolton/parlex/lexeme"
+	"github.com/adamcolton/parlex/tree"
+	"github.com/stretchr/testify/assert"
+)
+
+const expr = `
+  op: "+" {
+    number: "1"
+    op: "*" {
+      number: "2"
+      number: "3"
+    }
+    comment: "x"
+  }
+`
+
+// node is a minimal parlex.ParseNode that does not track it's parent.
+type node struct {
+	*lexeme.Lexeme
+	c []*node
+}
+
+func (n *node) Parent() parlex.ParseNode     { return nil }
+func (n *node) Children() int                { return len(n.c) }
+func (n *node) Child(i int) parlex.ParseNode { return n.c[i] }
+
+func toNode(pn *tree.PN) *node {
+	n := &node{Lexeme: lexeme.New(pn.Kind()).Set(pn.Value())}
+	for _, c := range pn.C {
+		n.c = append(n.c, toNode(c))
+	}
+	return n
+}
+
+func TestWalk(t *testing.T) {
+	pn, err := tree.New(expr)
+	assert.NoError(t, err)
+
+	for _, root := range []parlex.ParseNode{pn, toNode(pn)} {
+		var enter, exit []string
+		Walk(root, Visitor{
+			Enter: func(c *Cursor) {
+				s := c.Node().Value()
+				if c.Parent() != nil {
+					s = c.Parent().Node().Value() + "/" + strconv.Itoa(c.Index()) + ":" + s
+				}
+				enter = append(enter, s)
+				if c.Node().Value() == "*" {
+					c.SkipChildren()
+				}
+			},
+			Exit: func(c *Cursor) {
+				exit = append(exit, strconv.Itoa(c.Depth())+c.Node().Value())
+			},
+		})
+		assert.Equal(t, []string{"+", "+/0:1", "+/1:*", "+/2:x"}, enter)
+		assert.Equal(t, []string{"11", "1*", "1x", "0+"}, exit)
+	}
+
+	assert.Panics(t, func() {
+		Walk(pn, Visitor{
+			Enter: func(c *Cursor) { c.Remove() },
+		})
+	})
+}
+
+func TestApply(t *testing.T) {
+	pn, err := tree.New(expr)
+	assert.NoError(t, err)
+
+	out := Apply(toNode(pn), Visitor{
+		Enter: func(c *Cursor) {
+			switch c.Node().Kind().String() {
+			case "comment":
+				c.Remove()
+			case "number":
+				if c.Node().Value() == "2" {
+					c.Replace(&tree.PN{Lexeme: lexeme.String("number").Set("5")})
+				}
+			}
+		},
+		Exit: func(c *Cursor) {
+			if c.Node().Kind().String() == "op" && c.Node().Value() == "*" {
+				pn := c.PN()
+				c.Replace(&tree.PN{
+					Lexeme: lexeme.String("group"),
+					C:      []*tree.PN{pn},
+				})
+			}
+		},
+	})
+	expected, err := tree.New(`
+    op: "+" {
+      number: "1"
+      group {
+        op: "*" {
+          number: "5"
+          number: "3"
+        }
+      }
+    }
+  `)
+	assert.NoError(t, err)
+	assert.Equal(t, expected.String(), out.String())
+	assert.Equal(t, out, out.C[1].P)
+	assert.Equal(t, out.C[1], out.C[1].C[0].P)
+
+	assert.Nil(t, Apply(pn, Visitor{
+		Enter: func(c *Cursor) { c.Remove() },
+	}))
+}
+
+func eval(node parlex.ParseNode, cs []interface{}) interface{} {
+	switch node.Kind().String() {
+	case "number":
+		i, _ := strconv.Atoi(node.Value())
+		return i
+	case "op":
+		out := cs[0].(int)
+		for _, c := range cs[1:] {
+			i, ok := c.(int)
+			if !ok {
+				continue
+			}
+			if node.Value() == "+" {
+				out += i
+			} else {
+				out *= i
+			}
+		}
+		return out
+	}
+	return node.Value()
+}
+
+func TestFold(t *testing.T) {
+	pn, err := tree.New(expr)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, Fold(pn, eval))
+	assert.Equal(t, 7, Fold(toNode(pn), eval))
+}
+
+func TestRewrite(t *testing.T) {
+	pn, err := tree.New(expr)
+	assert.NoError(t, err)
+
+	r := &Rewriter{}
+	r.Add(MustPattern(`comment`), func(node *tree.PN) {
+		node.Lexeme = lexeme.String("removed")
+	}).Add(MustPattern(`
+    op {
+      number
+      number
+    }
+  `), func(node *tree.PN) {
+		node.Lexeme = lexeme.String("number").Set(strconv.Itoa(eval(node, []interface{}{
+			eval(node.C[0], nil),
+			eval(node.C[1], nil),
+		}).(int)))
+		node.C = nil
+	}).Add(MustPattern(`
+    _ {
+      _
+      _
+      removed
+    }
+  `), tree.RemoveChild(-1))
+
+	out, err := r.Rewrite(toNode(pn))
+	assert.NoError(t, err)
+	assert.Equal(t, "number: \"7\"\n", out.String())
+
+	r = &Rewriter{MaxPasses: 3}
+	r.Add(nil, func(node *tree.PN) {
+		node.C = append(node.C, &tree.PN{Lexeme: lexeme.String("x")})
+	})
+	_, err = r.Rewrite(pn)
+	assert.Equal(t, ErrNoFixpoint, err)
+}
+
+func TestPattern(t *testing.T) {
+	pn, err := tree.New(expr)
+	assert.NoError(t, err)
+
+	tt := []struct {
+		pattern  string
+		expected bool
+	}{
+		{`op`, true},
+		{`op: "+"`, true},
+		{`op: "*"`, false},
+		{`
+      _ {
+        _
+        _
+        _
+      }`, true},
+		{`
+      _ {
+        _
+        _
+      }`, false},
+		{`
+      _ {
+        number
+        op: "*"
+        _
+      }`, true},
+		{`
+      _ {
+        number
+        op: "+"
+        _
+      }`, false},
+		{`
+      _ {
+        _
+        op {
+          _
+          number: "3"
+        }
+        _
+      }`, true},
+	}
+	for _, tc := range tt {
+		assert.Equal(t, tc.expected, MustPattern(tc.pattern)(pn), tc.pattern)
+	}
+}